@@ -0,0 +1,205 @@
+package monerium
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+)
+
+// Amount represents an exact decimal amount of money, e.g. a balance.
+// The zero value represents 0. Amount is immutable, operations return a new Amount.
+type Amount struct {
+	r *big.Rat
+}
+
+// ParseAmount parses decimal amount as returned and accepted by Monerium API, e.g. "1.23".
+func ParseAmount(s string) (Amount, error) {
+	r, err := parseAmount(s)
+	if err != nil {
+		return Amount{}, err
+	}
+
+	return Amount{r}, nil
+}
+
+// String formats a as a decimal with no trailing zeros, e.g. "1.5".
+func (a Amount) String() string {
+	return formatAmount(a.rat())
+}
+
+// Add returns the sum a+b.
+func (a Amount) Add(b Amount) Amount {
+	return Amount{new(big.Rat).Add(a.rat(), b.rat())}
+}
+
+// Sub returns the difference a-b.
+func (a Amount) Sub(b Amount) Amount {
+	return Amount{new(big.Rat).Sub(a.rat(), b.rat())}
+}
+
+// Cmp compares a and b and returns -1 if a < b, 0 if a == b and +1 if a > b.
+func (a Amount) Cmp(b Amount) int {
+	return a.rat().Cmp(b.rat())
+}
+
+// Sign returns -1 if a < 0, 0 if a == 0 and +1 if a > 0.
+func (a Amount) Sign() int {
+	return a.rat().Sign()
+}
+
+// Rat returns a as a new big.Rat.
+func (a Amount) Rat() *big.Rat {
+	return new(big.Rat).Set(a.rat())
+}
+
+// ValidateFor checks that a has no more decimal places than currency c allows, see DecimalsFor.
+// Amounts in currencies unknown to the SDK aren't checked.
+func (a Amount) ValidateFor(c Currency) error {
+	d := DecimalsFor(c)
+	if d < 0 {
+		return nil
+	}
+	x := new(big.Rat).Mul(a.rat(), new(big.Rat).SetInt(pow10(uint(d))))
+	if !x.IsInt() {
+		return fmt.Errorf("amount %s has more than %d decimal places allowed for %s", a, d, c)
+	}
+
+	return nil
+}
+
+// DecimalsFor returns the number of decimal places of amounts in fiat currency c, e.g. 2 for EUR and 0 for ISK.
+// It applies to orders, which move fiat money; e-money tokens have more decimals, see Token.Decimals.
+// It returns -1 for currencies unknown to the SDK.
+func DecimalsFor(c Currency) int {
+	switch c {
+	case CurrencyISK:
+		return 0
+	case CurrencyEUR, CurrencyUSD, CurrencyGBP:
+		return 2
+	default:
+		return -1
+	}
+}
+
+// RoundingMode tells how amounts are rounded when they have more decimal places than a conversion allows.
+type RoundingMode int
+
+const (
+	// RoundingExact fails conversions which would lose precision. It's the default.
+	RoundingExact RoundingMode = iota
+	// RoundingTruncate rounds toward zero.
+	RoundingTruncate
+	// RoundingHalfEven rounds to the nearest value, ties to the even one (banker's rounding).
+	RoundingHalfEven
+)
+
+// ToBaseUnits converts a to the integer number of base units of a token with decimals, e.g. 1.5 EURe
+// with 18 decimals (see Token.Decimals) to 1500000000000000000. Amounts with more decimal places
+// are rounded according to mode.
+func (a Amount) ToBaseUnits(decimals uint, mode RoundingMode) (*big.Int, error) {
+	x := new(big.Rat).Mul(a.rat(), new(big.Rat).SetInt(pow10(decimals)))
+	if x.IsInt() {
+		return new(big.Int).Set(x.Num()), nil
+	}
+
+	// Quo truncates toward zero, rem keeps the sign of a.
+	q, rem := new(big.Int).QuoRem(x.Num(), x.Denom(), new(big.Int))
+	switch mode {
+	case RoundingTruncate:
+		return q, nil
+	case RoundingHalfEven:
+		// Compare 2*|rem| with the denominator to find out which neighbor is nearer.
+		c := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(x.Denom())
+		if c > 0 || c == 0 && q.Bit(0) == 1 {
+			q.Add(q, big.NewInt(int64(a.Sign())))
+		}
+		return q, nil
+	default:
+		return nil, fmt.Errorf("amount %s has more than %d decimal places", a, decimals)
+	}
+}
+
+// FromBaseUnits converts the integer number of base units of a token with decimals to an Amount,
+// e.g. 1500000000000000000 with 18 decimals to 1.5. The conversion is exact.
+func FromBaseUnits(units *big.Int, decimals uint) Amount {
+	return Amount{new(big.Rat).SetFrac(units, pow10(decimals))}
+}
+
+// pow10 returns 10 to the power of n.
+func pow10(n uint) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *Amount) UnmarshalText(bs []byte) error {
+	p, err := ParseAmount(string(bs))
+	if err != nil {
+		return err
+	}
+	*a = p
+
+	return nil
+}
+
+// rat returns underlying value, which must not be modified.
+func (a Amount) rat() *big.Rat {
+	if a.r == nil {
+		return new(big.Rat)
+	}
+
+	return a.r
+}
+
+// amountRegexp matches decimal amounts as returned and accepted by Monerium API, e.g. "1.23" or "-5".
+var amountRegexp = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// parseAmount parses a decimal amount as returned and accepted by Monerium API, e.g. "1.23".
+// Other forms accepted by big.Rat, e.g. fractions, exponents or hexadecimal numbers, are rejected.
+func parseAmount(s string) (*big.Rat, error) {
+	if !amountRegexp.MatchString(s) {
+		return nil, fmt.Errorf("invalid amount: %q", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount: %q", s)
+	}
+
+	return r, nil
+}
+
+// equalAmounts reports whether a and b represent the same decimal amount, e.g. "1" and "1.00".
+// Malformed amounts are never equal.
+func equalAmounts(a, b string) bool {
+	ra, err := parseAmount(a)
+	if err != nil {
+		return false
+	}
+	rb, err := parseAmount(b)
+	if err != nil {
+		return false
+	}
+
+	return ra.Cmp(rb) == 0
+}
+
+// maxAmountDecimals is the maximum number of decimal places used when formatting amounts.
+// It matches the decimals of Monerium e-money tokens.
+const maxAmountDecimals = 18
+
+// formatAmount formats r as a decimal amount with no trailing zeros, e.g. "1.5".
+func formatAmount(r *big.Rat) string {
+	ten := big.NewRat(10, 1)
+	x := new(big.Rat).Set(r)
+	d := 0
+	for !x.IsInt() && d < maxAmountDecimals {
+		x.Mul(x, ten)
+		d++
+	}
+
+	return r.FloatString(d)
+}
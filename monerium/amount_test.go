@@ -0,0 +1,123 @@
+package monerium
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAmountValidateForCurrencyDecimals(t *testing.T) {
+	tests := []struct {
+		currency Currency
+		amount   string
+		wantErr  bool
+	}{
+		{CurrencyISK, "10", false},
+		{CurrencyISK, "10.00", false},
+		{CurrencyISK, "10.50", true},
+		{CurrencyISK, "0.1", true},
+		{CurrencyEUR, "10", false},
+		{CurrencyEUR, "10.5", false},
+		{CurrencyEUR, "10.50", false},
+		{CurrencyEUR, "10.505", true},
+		{CurrencyGBP, "0.01", false},
+		{CurrencyGBP, "0.001", true},
+		{"xyz", "0.000001", false},
+	}
+	for _, tt := range tests {
+		a, err := ParseAmount(tt.amount)
+		if err != nil {
+			t.Fatalf("ParseAmount(%q): %v", tt.amount, err)
+		}
+		if err := a.ValidateFor(tt.currency); (err != nil) != tt.wantErr {
+			t.Errorf("%s %s: ValidateFor = %v, want error: %v", tt.amount, tt.currency, err, tt.wantErr)
+		}
+	}
+
+	for c, want := range map[Currency]int{CurrencyISK: 0, CurrencyEUR: 2, CurrencyUSD: 2, CurrencyGBP: 2, "xyz": -1} {
+		if got := DecimalsFor(c); got != want {
+			t.Errorf("DecimalsFor(%s) = %d, want %d", c, got, want)
+		}
+	}
+}
+
+func TestPlaceOrderRequestValidateChecksAmountDecimals(t *testing.T) {
+	cp, err := NewCounterpart("IS140159260076545510730339", "Jane", "Doe", "IS")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		currency Currency
+		amount   string
+		wantErr  bool
+	}{
+		{CurrencyISK, "1000", false},
+		{CurrencyISK, "10.50", true},
+		{CurrencyEUR, "10.50", false},
+		{CurrencyEUR, "10.505", true},
+		{CurrencyEUR, "-5", true},
+		{CurrencyEUR, "0", true},
+		{CurrencyEUR, "0.00", true},
+		{CurrencyEUR, "1e2", true},
+		{CurrencyEUR, "2/2", true},
+	}
+	for _, tt := range tests {
+		req := &PlaceOrderRequest{
+			Kind:        OrderKindRedeem,
+			Amount:      tt.amount,
+			Currency:    tt.currency,
+			Chain:       ChainEthereum,
+			Address:     "0x1",
+			Counterpart: cp,
+			Message:     BuildOrderMessage(tt.currency, tt.amount, cp.Identifier.IBAN, time.Now()),
+			Signature:   "0xsig",
+		}
+		if err := req.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s %s: Validate() = %v, want error: %v", tt.amount, tt.currency, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "1", want: "1"},
+		{in: "1.23", want: "1.23"},
+		{in: "1.50", want: "1.5"},
+		{in: "-5", want: "-5"},
+		{in: "0.000000000000000001", want: "0.000000000000000001"},
+		{in: "1/3", wantErr: true},
+		{in: "0x10", wantErr: true},
+		{in: "0x10/1", wantErr: true},
+		{in: "1e2", wantErr: true},
+		{in: "1E-2", wantErr: true},
+		{in: "1.", wantErr: true},
+		{in: ".5", wantErr: true},
+		{in: "+1", wantErr: true},
+		{in: " 1", wantErr: true},
+		{in: "1,5", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		a, err := ParseAmount(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAmount(%q) = %s, %v, want error: %v", tt.in, a, err, tt.wantErr)
+			continue
+		}
+		if err == nil && a.String() != tt.want {
+			t.Errorf("ParseAmount(%q) = %s, want %s", tt.in, a, tt.want)
+		}
+
+		var u Amount
+		if err := u.UnmarshalText([]byte(tt.in)); (err != nil) != tt.wantErr {
+			t.Errorf("UnmarshalText(%q) = %v, want error: %v", tt.in, err, tt.wantErr)
+		}
+	}
+
+	if equalAmounts("2/2", "1") || equalAmounts("1e2", "100") {
+		t.Error("non-decimal amounts compared equal")
+	}
+}
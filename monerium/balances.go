@@ -5,17 +5,29 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"sync"
+	"time"
 )
 
 // GetBalancesForProfile retrieves balance for every account of a profile.
 // Each account represent one token, on a chain and network.
 func (c *Client) GetBalancesForProfile(ctx context.Context, req *GetBalancesForProfileRequest) ([]*ProfileBalance, error) {
+	if req != nil && req.ProfileID == "" {
+		id, err := c.profileID(ctx, req.ProfileID)
+		if err != nil {
+			return nil, err
+		}
+		r := *req
+		r.ProfileID = id
+		req = &r
+	}
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
 
 	path := fmt.Sprintf("/profiles/%s/balances", req.ProfileID)
-	bs, err := c.get(ctx, path)
+	bs, err := c.get(ctx, path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -24,6 +36,7 @@ func (c *Client) GetBalancesForProfile(ctx context.Context, req *GetBalancesForP
 	if err = json.Unmarshal(bs, &pbs); err != nil {
 		return nil, err
 	}
+	c.warnInvalidBalances(ctx, pbs)
 	return pbs, nil
 }
 
@@ -37,16 +50,112 @@ func (r *GetBalancesForProfileRequest) Validate() error {
 	if r == nil {
 		return errors.New("GetBalancesForProfileRequest is required")
 	}
+	if r.ProfileID == "" {
+		return errors.New("empty profileID")
+	}
 
 	return nil
 }
 
+// WatchBalances polls balances of the profile identified by profileID every interval.
+// The first snapshot is always emitted, the following ones only when any amount or currency changed.
+// Polling failures are sent over the error channel and polling continues.
+// Both channels are closed when ctx is done or the Client is closed.
+// A non-positive interval is reported as the only error, with both channels closed right away.
+func (c *Client) WatchBalances(ctx context.Context, profileID string, interval time.Duration) (<-chan []*ProfileBalance, <-chan error) {
+	if interval <= 0 {
+		bc := make(chan []*ProfileBalance)
+		ec := make(chan error, 1)
+		ec <- fmt.Errorf("non-positive poll interval: %s", interval)
+		close(bc)
+		close(ec)
+		return bc, ec
+	}
+
+	bc := make(chan []*ProfileBalance)
+	ec := make(chan error)
+
+	ctx, cancel := c.withClose(ctx)
+	go func() {
+		defer cancel()
+		defer close(bc)
+		defer close(ec)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var last map[string]string
+		for {
+			pbs, err := c.GetBalancesForProfile(ctx, &GetBalancesForProfileRequest{ProfileID: profileID})
+			switch {
+			case err != nil:
+				if ctx.Err() == nil {
+					select {
+					case ec <- err:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case last == nil || !equalBalances(last, balancesKeys(pbs)):
+				last = balancesKeys(pbs)
+				select {
+				case bc <- pbs:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return bc, ec
+}
+
+// balancesKeys maps every balance, identified by account and currency, to its normalized amount.
+func balancesKeys(pbs []*ProfileBalance) map[string]string {
+	ks := make(map[string]string)
+	for _, pb := range pbs {
+		for _, b := range pb.Balances {
+			k := fmt.Sprintf("%s/%s/%s/%s", pb.Address, pb.Chain, pb.Network, b.Currency)
+			amount := b.Amount
+			if r, err := parseAmount(amount); err == nil {
+				amount = formatAmount(r)
+			}
+			ks[k] = amount
+		}
+	}
+
+	return ks
+}
+
+// equalBalances reports whether balances keyed by balancesKeys are equal.
+func equalBalances(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+
+	return true
+}
+
 // GetBalances retrieves balance for every account of the default profile.
 // Each account represent one token, on a chain and network.
-func (c *Client) GetBalances(ctx context.Context) ([]*ProfileBalance, error) {
+func (c *Client) GetBalances(ctx context.Context, opts ...RequestOption) ([]*ProfileBalance, error) {
+	ctx, cancel := applyRequestOptions(ctx, opts)
+	defer cancel()
+
 	path := "/balances"
 
-	bs, err := c.get(ctx, path)
+	bs, err := c.get(ctx, path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -54,16 +163,28 @@ func (c *Client) GetBalances(ctx context.Context) ([]*ProfileBalance, error) {
 	if err = json.Unmarshal(bs, &pbs); err != nil {
 		return nil, err
 	}
+	c.warnInvalidBalances(ctx, pbs)
 
 	return pbs, nil
 }
 
 // GetTokens retrieves information about the emoney tokens with tickers, symbols, decimals, token contract
 // address and the network and chain information, we currently support Ethereum and Polygon.
-func (c *Client) GetTokens(ctx context.Context) ([]*Token, error) {
+// The result is cached if the Client is created WithTokenCache.
+func (c *Client) GetTokens(ctx context.Context, opts ...RequestOption) ([]*Token, error) {
+	ctx, cancel := applyRequestOptions(ctx, opts)
+	defer cancel()
+
+	if c.isClosed() {
+		return nil, ErrClientClosed
+	}
+	if ts, ok := c.tokens.get(); ok {
+		return ts, nil
+	}
+
 	path := "/tokens"
 
-	bs, err := c.get(ctx, path)
+	bs, err := c.get(ctx, path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -71,19 +192,226 @@ func (c *Client) GetTokens(ctx context.Context) ([]*Token, error) {
 	if err = json.Unmarshal(bs, &ts); err != nil {
 		return nil, err
 	}
+	c.tokens.set(ts)
 
 	return ts, nil
 }
 
+// GetTokenBySymbol retrieves the token with symbol on chain and network.
+// It returns an error wrapping ErrNotFound if there's no such token.
+func (c *Client) GetTokenBySymbol(ctx context.Context, symbol Symbol, chain Chain, network Network) (*Token, error) {
+	ts, err := c.GetTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range ts {
+		if t.Symbol == symbol && t.Chain == chain && t.Network == network {
+			return t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("token %s on %s %s: %w", symbol, chain, network, ErrNotFound)
+}
+
+// GetDecimalsMap retrieves decimals of tokens per currency and chain, built from GetTokens.
+// Tokens of the same currency and chain have the same decimals on every network.
+// The tokens are cached if the Client is created WithTokenCache; the map is built anew on every call,
+// so it can be modified by the caller.
+func (c *Client) GetDecimalsMap(ctx context.Context) (map[Currency]map[Chain]uint, error) {
+	ts, err := c.GetTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[Currency]map[Chain]uint)
+	for _, t := range ts {
+		if m[t.Currency] == nil {
+			m[t.Currency] = make(map[Chain]uint)
+		}
+		m[t.Currency][t.Chain] = t.Decimals
+	}
+
+	return m, nil
+}
+
+// AccountTokenKey identifies an account linked to an address, see ProfileTokens.
+type AccountTokenKey struct {
+	Address  string
+	Currency Currency
+	Chain    Chain
+}
+
+// ProfileTokens retrieves tokens of accounts of the profile identified by profileID, keyed by account
+// address, currency and chain. An address usually holds several tokens, one per currency it's linked for,
+// so the address alone doesn't identify a token. Accounts not linked to an address,
+// and accounts without a matching token, are skipped.
+// The tokens are cached if the Client is created WithTokenCache.
+func (c *Client) ProfileTokens(ctx context.Context, profileID string) (map[AccountTokenKey]*Token, error) {
+	p, err := c.GetProfile(ctx, &GetProfileRequest{ProfileID: profileID})
+	if err != nil {
+		return nil, err
+	}
+	ts, err := c.GetTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[AccountTokenKey]*Token)
+	for _, a := range p.Accounts {
+		if a.Address == "" {
+			continue
+		}
+		for _, t := range ts {
+			if t.Currency == a.Currency && t.Chain == a.Chain && t.Network == a.Network {
+				m[AccountTokenKey{Address: a.Address, Currency: a.Currency, Chain: a.Chain}] = t
+				break
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// tokenCache caches tokens for ttl. Zero ttl disables caching.
+type tokenCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	tokens    []*Token
+	fetchedAt time.Time
+}
+
+// get returns copies of cached tokens, unless they're missing or expired.
+func (tc *tokenCache) get() ([]*Token, bool) {
+	if tc.ttl <= 0 {
+		return nil, false
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.tokens == nil || time.Since(tc.fetchedAt) >= tc.ttl {
+		return nil, false
+	}
+
+	return copyTokens(tc.tokens), true
+}
+
+// set caches copies of ts.
+func (tc *tokenCache) set(ts []*Token) {
+	if tc.ttl <= 0 {
+		return
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.tokens = copyTokens(ts)
+	tc.fetchedAt = time.Now()
+}
+
+// copyTokens returns a deep copy of ts, so that callers can't modify cached tokens.
+func copyTokens(ts []*Token) []*Token {
+	cp := make([]*Token, len(ts))
+	for i, t := range ts {
+		if t == nil {
+			continue
+		}
+		tt := *t
+		cp[i] = &tt
+	}
+
+	return cp
+}
+
 // ProfileBalance represents balances of a profile identified by ProfileID.
+// Chain and Network unknown to the SDK are kept verbatim, see Chain.IsKnown and Network.IsKnown.
 type ProfileBalance struct {
 	ProfileID string     `json:"id,omitempty"`
 	Address   string     `json:"address,omitempty"`
-	Chain     string     `json:"chain,omitempty"`
-	Network   string     `json:"network,omitempty"`
+	Chain     Chain      `json:"chain,omitempty"`
+	Network   Network    `json:"network,omitempty"`
 	Balances  []*Balance `json:"balances,omitempty"`
 }
 
+// Validate checks that Chain and Network belong together, returning *ChainNetworkMismatchError if they don't.
+// Chains and networks unknown to the SDK can't be checked, see Chain.IsKnown and Network.IsKnown.
+func (pb *ProfileBalance) Validate() error {
+	if !pb.Chain.IsKnown() || !pb.Network.IsKnown() || pb.Chain.HasNetwork(pb.Network) {
+		return nil
+	}
+
+	return &ChainNetworkMismatchError{Chain: pb.Chain, Network: pb.Network}
+}
+
+// warnInvalidBalances logs a warning for every balance of pbs failing ProfileBalance.Validate.
+// Such balances are still returned, as the inconsistency is on the API side.
+func (c *Client) warnInvalidBalances(ctx context.Context, pbs []*ProfileBalance) {
+	for _, pb := range pbs {
+		if pb == nil {
+			continue
+		}
+		if err := pb.Validate(); err != nil {
+			c.logger.Warnf("monerium: balances of address %s: %v%s", pb.Address, err, logLabels(ctx))
+		}
+	}
+}
+
+// Total returns the sum of balances in currency. It fails if any of these balances is malformed.
+func (pb *ProfileBalance) Total(currency Currency) (Amount, error) {
+	var total Amount
+	for _, b := range pb.Balances {
+		if Currency(b.Currency) != currency {
+			continue
+		}
+		a, err := ParseAmount(b.Amount)
+		if err != nil {
+			return Amount{}, fmt.Errorf("balance of %s on %s: %w", pb.Address, pb.Chain, err)
+		}
+		total = total.Add(a)
+	}
+
+	return total, nil
+}
+
+// SortBalances sorts pbs in place by chain, network and address, and balances of every account by currency.
+// The API returns accounts in no particular order; GetBalances and GetBalancesForProfile keep it as is.
+func SortBalances(pbs []*ProfileBalance) {
+	sort.SliceStable(pbs, func(i, j int) bool {
+		a, b := pbs[i], pbs[j]
+		if a.Chain != b.Chain {
+			return a.Chain < b.Chain
+		}
+		if a.Network != b.Network {
+			return a.Network < b.Network
+		}
+
+		return a.Address < b.Address
+	})
+	for _, pb := range pbs {
+		sort.SliceStable(pb.Balances, func(i, j int) bool {
+			return pb.Balances[i].Currency < pb.Balances[j].Currency
+		})
+	}
+}
+
+// AggregateByChain sums balances of all accounts per chain and currency.
+// It fails on the first malformed balance.
+func AggregateByChain(pbs []*ProfileBalance) (map[Chain]map[Currency]Amount, error) {
+	agg := make(map[Chain]map[Currency]Amount)
+	for _, pb := range pbs {
+		for _, b := range pb.Balances {
+			a, err := ParseAmount(b.Amount)
+			if err != nil {
+				return nil, fmt.Errorf("balance of %s on %s: %w", pb.Address, pb.Chain, err)
+			}
+			if agg[pb.Chain] == nil {
+				agg[pb.Chain] = make(map[Currency]Amount)
+			}
+			cur := Currency(b.Currency)
+			agg[pb.Chain][cur] = agg[pb.Chain][cur].Add(a)
+		}
+	}
+
+	return agg, nil
+}
+
 // Balance represents a balance - amount and currency.
 type Balance struct {
 	Amount   string `json:"amount,omitempty"`
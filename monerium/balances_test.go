@@ -0,0 +1,292 @@
+package monerium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatchBalancesRejectsNonPositiveInterval(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+	}))
+
+	bc, ec := c.WatchBalances(context.Background(), "p1", 0)
+	if err, ok := <-ec; !ok || err == nil {
+		t.Fatalf("expected error, got %v", err)
+	}
+	if _, ok := <-ec; ok {
+		t.Error("error channel not closed")
+	}
+	if _, ok := <-bc; ok {
+		t.Error("balances channel not closed")
+	}
+}
+
+func TestWatchBalancesEmitsChanges(t *testing.T) {
+	amounts := []string{"1", "1.00", "2"}
+	var polls int32
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt32(&polls, 1)) - 1
+		if i >= len(amounts) {
+			i = len(amounts) - 1
+		}
+		writeJSON(t, w, []*ProfileBalance{{ProfileID: "p1", Address: "0x1", Chain: ChainEthereum, Network: NetworkMainnet, Balances: []*Balance{{Currency: "eur", Amount: amounts[i]}}}})
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bc, _ := c.WatchBalances(ctx, "p1", time.Millisecond)
+
+	var got []string
+	for pbs := range bc {
+		got = append(got, pbs[0].Balances[0].Amount)
+		if len(got) == 2 {
+			cancel()
+		}
+	}
+	if len(got) != 2 || got[0] != "1" || got[1] != "2" {
+		t.Errorf("emitted amounts = %v, want [1 2]", got)
+	}
+}
+
+// multiChainBalances returns balances of one profile on several chains, in several currencies.
+func multiChainBalances() []*ProfileBalance {
+	return []*ProfileBalance{
+		{ProfileID: "p1", Address: "0x1", Chain: ChainEthereum, Network: NetworkMainnet, Balances: []*Balance{
+			{Currency: "eur", Amount: "1.5"},
+			{Currency: "usd", Amount: "10"},
+		}},
+		{ProfileID: "p1", Address: "0x2", Chain: ChainEthereum, Network: NetworkMainnet, Balances: []*Balance{
+			{Currency: "eur", Amount: "0.25"},
+		}},
+		{ProfileID: "p1", Address: "0x1", Chain: ChainPolygon, Network: NetworkMainnet, Balances: []*Balance{
+			{Currency: "eur", Amount: "3"},
+			{Currency: "gbp", Amount: "0.000000000000000001"},
+		}},
+		{ProfileID: "p1", Address: "0x1", Chain: ChainGnosis, Network: NetworkMainnet},
+	}
+}
+
+func TestProfileBalanceTotal(t *testing.T) {
+	pb := &ProfileBalance{Address: "0x1", Chain: ChainEthereum, Balances: []*Balance{
+		{Currency: "eur", Amount: "1.5"},
+		{Currency: "usd", Amount: "10"},
+		{Currency: "eur", Amount: "0.5"},
+	}}
+
+	for c, want := range map[Currency]string{CurrencyEUR: "2", CurrencyUSD: "10", CurrencyGBP: "0"} {
+		got, err := pb.Total(c)
+		if err != nil {
+			t.Fatalf("Total(%s): %v", c, err)
+		}
+		if got.String() != want {
+			t.Errorf("Total(%s) = %s, want %s", c, got, want)
+		}
+	}
+
+	pb.Balances = append(pb.Balances, &Balance{Currency: "eur", Amount: "1,5"})
+	if _, err := pb.Total(CurrencyEUR); err == nil {
+		t.Error("expected error for malformed amount")
+	}
+	if _, err := pb.Total(CurrencyUSD); err != nil {
+		t.Errorf("Total of another currency: %v", err)
+	}
+}
+
+func TestAggregateByChain(t *testing.T) {
+	agg, err := AggregateByChain(multiChainBalances())
+	if err != nil {
+		t.Fatalf("AggregateByChain: %v", err)
+	}
+
+	want := map[Chain]map[Currency]string{
+		ChainEthereum: {CurrencyEUR: "1.75", CurrencyUSD: "10"},
+		ChainPolygon:  {CurrencyEUR: "3", CurrencyGBP: "0.000000000000000001"},
+	}
+	if len(agg) != len(want) {
+		t.Fatalf("got %d chains, want %d: %v", len(agg), len(want), agg)
+	}
+	for chain, cs := range want {
+		if len(agg[chain]) != len(cs) {
+			t.Errorf("%s: got %d currencies, want %d", chain, len(agg[chain]), len(cs))
+		}
+		for c, a := range cs {
+			if got := agg[chain][c].String(); got != a {
+				t.Errorf("%s %s = %s, want %s", chain, c, got, a)
+			}
+		}
+	}
+
+	pbs := multiChainBalances()
+	pbs[2].Balances[0].Amount = "abc"
+	if _, err := AggregateByChain(pbs); err == nil {
+		t.Error("expected error for malformed amount")
+	}
+}
+
+func TestProfileBalanceUnmarshalsNetworks(t *testing.T) {
+	tests := []struct {
+		in      string
+		chain   Chain
+		network Network
+		known   bool
+	}{
+		{`{"chain":"ethereum","network":"mainnet"}`, ChainEthereum, NetworkMainnet, true},
+		{`{"chain":"gnosis","network":"chiado"}`, ChainGnosis, NetworkChiado, true},
+		{`{"chain":"arbitrum","network":"sepolia"}`, "arbitrum", "sepolia", false},
+		{`{"chain":"polygon","network":"amoy"}`, ChainPolygon, "amoy", false},
+	}
+	for _, tt := range tests {
+		var pb ProfileBalance
+		if err := json.Unmarshal([]byte(tt.in), &pb); err != nil {
+			t.Fatalf("unmarshal %s: %v", tt.in, err)
+		}
+		if pb.Chain != tt.chain || pb.Network != tt.network {
+			t.Errorf("%s: got %s/%s, want %s/%s", tt.in, pb.Chain, pb.Network, tt.chain, tt.network)
+		}
+		if known := pb.Chain.IsKnown() && pb.Network.IsKnown(); known != tt.known {
+			t.Errorf("%s: known = %v, want %v", tt.in, known, tt.known)
+		}
+	}
+}
+
+func TestSortBalancesIsStable(t *testing.T) {
+	// key describes the order of accounts and their balances.
+	key := func(pbs []*ProfileBalance) string {
+		var s string
+		for _, pb := range pbs {
+			s += fmt.Sprintf("%s/%s/%s:", pb.Chain, pb.Network, pb.Address)
+			for _, b := range pb.Balances {
+				s += b.Currency + ","
+			}
+			s += " "
+		}
+		return s
+	}
+
+	want := "ethereum/mainnet/0x1:eur,usd, ethereum/mainnet/0x2:eur, gnosis/mainnet/0x1: polygon/mainnet/0x1:eur,gbp, "
+	for i := 0; i < 20; i++ {
+		pbs := multiChainBalances()
+		rnd := rand.New(rand.NewSource(int64(i)))
+		rnd.Shuffle(len(pbs), func(i, j int) { pbs[i], pbs[j] = pbs[j], pbs[i] })
+		for _, pb := range pbs {
+			rnd.Shuffle(len(pb.Balances), func(i, j int) { pb.Balances[i], pb.Balances[j] = pb.Balances[j], pb.Balances[i] })
+		}
+
+		SortBalances(pbs)
+		if got := key(pbs); got != want {
+			t.Fatalf("seed %d: sorted to %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestProfileTokens(t *testing.T) {
+	var tokenFetches int32
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/profiles/p1":
+			writeJSON(t, w, &Profile{ID: "p1", Accounts: []Account{
+				{Address: "0x1", Chain: ChainEthereum, Network: NetworkMainnet, Currency: CurrencyEUR},
+				{Address: "0x1", Chain: ChainEthereum, Network: NetworkMainnet, Currency: CurrencyUSD},
+				{Address: "0x2", Chain: ChainGnosis, Network: NetworkChiado, Currency: CurrencyEUR},
+				{Address: "0x3", Chain: ChainPolygon, Network: NetworkMainnet, Currency: CurrencyISK},
+				{Currency: CurrencyEUR, IBAN: "GR1601101250000000012300695"},
+			}})
+		case "/tokens":
+			atomic.AddInt32(&tokenFetches, 1)
+			writeJSON(t, w, []*Token{
+				{Currency: CurrencyEUR, Symbol: "EURe", Chain: ChainEthereum, Network: NetworkMainnet, Address: "0xeur", Decimals: 18},
+				{Currency: CurrencyUSD, Symbol: "USDe", Chain: ChainEthereum, Network: NetworkMainnet, Address: "0xusd", Decimals: 18},
+				{Currency: CurrencyEUR, Symbol: "EURe", Chain: ChainGnosis, Network: NetworkMainnet, Address: "0xgno", Decimals: 18},
+				{Currency: CurrencyEUR, Symbol: "EURe", Chain: ChainGnosis, Network: NetworkChiado, Address: "0xchiado", Decimals: 18},
+			})
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+	}), WithTokenCache(time.Minute))
+
+	for i := 0; i < 2; i++ {
+		m, err := c.ProfileTokens(context.Background(), "p1")
+		if err != nil {
+			t.Fatalf("ProfileTokens: %v", err)
+		}
+
+		got := make(map[AccountTokenKey]string)
+		for k, tok := range m {
+			got[k] = tok.Address
+		}
+		want := map[AccountTokenKey]string{
+			{Address: "0x1", Currency: CurrencyEUR, Chain: ChainEthereum}: "0xeur",
+			{Address: "0x1", Currency: CurrencyUSD, Chain: ChainEthereum}: "0xusd",
+			{Address: "0x2", Currency: CurrencyEUR, Chain: ChainGnosis}:   "0xchiado",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("tokens by account = %v, want %v", got, want)
+		}
+	}
+	if n := atomic.LoadInt32(&tokenFetches); n != 1 {
+		t.Errorf("tokens fetched %d times, want 1 with token cache", n)
+	}
+}
+
+func TestProfileBalanceValidateChainNetwork(t *testing.T) {
+	tests := []struct {
+		chain    Chain
+		network  Network
+		mismatch bool
+	}{
+		{ChainEthereum, NetworkMainnet, false},
+		{ChainEthereum, NetworkGoerli, false},
+		{ChainPolygon, NetworkMumbai, false},
+		{ChainGnosis, NetworkChiado, false},
+		{ChainEthereum, NetworkMumbai, true},
+		{ChainGnosis, NetworkGoerli, true},
+		{"arbitrum", NetworkMumbai, false},
+		{ChainPolygon, "amoy", false},
+	}
+	for _, tt := range tests {
+		pb := &ProfileBalance{Chain: tt.chain, Network: tt.network}
+		err := pb.Validate()
+		var mismatch *ChainNetworkMismatchError
+		if errors.As(err, &mismatch) != tt.mismatch {
+			t.Errorf("%s/%s: Validate() = %v, want mismatch: %v", tt.chain, tt.network, err, tt.mismatch)
+			continue
+		}
+		if tt.mismatch && (mismatch.Chain != tt.chain || mismatch.Network != tt.network) {
+			t.Errorf("%s/%s: mismatch = %+v", tt.chain, tt.network, mismatch)
+		}
+	}
+}
+
+func TestGetBalancesWarnsAboutMismatchedNetwork(t *testing.T) {
+	l := &recordingLogger{}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, []*ProfileBalance{
+			{ProfileID: "p1", Address: "0x1", Chain: ChainEthereum, Network: NetworkMainnet},
+			{ProfileID: "p1", Address: "0x2", Chain: ChainEthereum, Network: NetworkChiado},
+		})
+	}), WithLogger(l))
+
+	pbs, err := c.GetBalances(context.Background())
+	if err != nil {
+		t.Fatalf("GetBalances: %v", err)
+	}
+	if len(pbs) != 2 {
+		t.Errorf("got %d balances, want the mismatched one kept too", len(pbs))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.warns) != 1 || !strings.Contains(l.warns[0], "0x2") || !strings.Contains(l.warns[0], "network chiado doesn't belong to chain ethereum") {
+		t.Errorf("warnings = %q, want one about 0x2", l.warns)
+	}
+}
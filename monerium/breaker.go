@@ -0,0 +1,161 @@
+package monerium
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by calls short-circuited by the circuit breaker, see WithCircuitBreaker.
+// When the breaker trips between retries of a call, the error wraps both ErrCircuitOpen and the last failure.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreakerSettings describes when the circuit breaker trips and how it recovers.
+//
+// Failures are the attempts a RetryPolicy would retry, i.e. network errors and 429 and 5xx responses;
+// every retry is an attempt on its own. Other errors, e.g. validation failures, count as successes
+// as they prove the API is reachable.
+type CircuitBreakerSettings struct {
+	// Window is the number of most recent attempts the failure rate is computed over.
+	// The breaker doesn't trip before Window attempts are made. Defaults to 1.
+	Window int
+	// FailureRate is the ratio of failed attempts within Window, from 0 to 1, at which the breaker trips.
+	// Defaults to 0.5.
+	FailureRate float64
+	// Cooldown is how long calls are short-circuited with ErrCircuitOpen once the breaker trips.
+	// After Cooldown a single probe call is let through: the breaker closes if it succeeds
+	// and trips again otherwise. Defaults to 30 seconds.
+	Cooldown time.Duration
+}
+
+// WithCircuitBreaker enables a circuit breaker shared by all calls of the Client.
+// It stops the Client from piling load on the API during an outage, failing fast with ErrCircuitOpen instead.
+// By default, there's no circuit breaker.
+func WithCircuitBreaker(s CircuitBreakerSettings) ClientOption {
+	return func(c *Client) {
+		c.breaker = newCircuitBreaker(s)
+	}
+}
+
+// circuitBreaker implements a circuit breaker described by CircuitBreakerSettings.
+// A nil *circuitBreaker lets all calls through.
+type circuitBreaker struct {
+	settings CircuitBreakerSettings
+
+	mu sync.Mutex
+	// outcomes is a ring buffer of recent attempts, true meaning failure.
+	outcomes []bool
+	next     int
+	count    int
+	failures int
+	// openedAt is when the breaker tripped, zero when closed.
+	openedAt time.Time
+	// probing reports whether the probe call is in flight.
+	probing bool
+	// generation changes every time the breaker trips or closes,
+	// so that attempts let through earlier can be told apart.
+	generation uint64
+}
+
+// breakerTicket identifies an attempt let through by circuitBreaker.allow.
+type breakerTicket struct {
+	generation uint64
+	probe      bool
+}
+
+// newCircuitBreaker creates a closed circuit breaker.
+func newCircuitBreaker(s CircuitBreakerSettings) *circuitBreaker {
+	if s.Window < 1 {
+		s.Window = 1
+	}
+	if s.FailureRate <= 0 {
+		s.FailureRate = 0.5
+	}
+	if s.Cooldown <= 0 {
+		s.Cooldown = 30 * time.Second
+	}
+
+	return &circuitBreaker{
+		settings: s,
+		outcomes: make([]bool, s.Window),
+	}
+}
+
+// allow reports ErrCircuitOpen if an attempt shouldn't be made.
+// Otherwise, it returns a ticket the outcome of the attempt is recorded with.
+func (cb *circuitBreaker) allow() (breakerTicket, error) {
+	if cb == nil {
+		return breakerTicket{}, nil
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.openedAt.IsZero() {
+		return breakerTicket{generation: cb.generation}, nil
+	}
+	if cb.probing || time.Since(cb.openedAt) < cb.settings.Cooldown {
+		return breakerTicket{}, ErrCircuitOpen
+	}
+	cb.probing = true
+
+	return breakerTicket{generation: cb.generation, probe: true}, nil
+}
+
+// record records outcome of an attempt let through by allow with ticket t.
+// Attempts which didn't complete, e.g. because their context was canceled, should be recorded as skipped.
+// Outcomes of attempts let through before the breaker last tripped or closed are ignored,
+// so only the probe decides whether an open breaker closes.
+func (cb *circuitBreaker) record(t breakerTicket, failed, skipped bool) {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if t.generation != cb.generation {
+		return
+	}
+	if t.probe {
+		cb.probing = false
+		if skipped {
+			return
+		}
+		if failed {
+			cb.openedAt = time.Now()
+			return
+		}
+		cb.reset()
+		return
+	}
+	if skipped {
+		return
+	}
+
+	if cb.count == len(cb.outcomes) {
+		if cb.outcomes[cb.next] {
+			cb.failures--
+		}
+	} else {
+		cb.count++
+	}
+	cb.outcomes[cb.next] = failed
+	if failed {
+		cb.failures++
+	}
+	cb.next = (cb.next + 1) % len(cb.outcomes)
+
+	if cb.count == len(cb.outcomes) && float64(cb.failures)/float64(cb.count) >= cb.settings.FailureRate {
+		cb.openedAt = time.Now()
+		cb.generation++
+	}
+}
+
+// reset closes the breaker and forgets recorded attempts.
+func (cb *circuitBreaker) reset() {
+	for i := range cb.outcomes {
+		cb.outcomes[i] = false
+	}
+	cb.next, cb.count, cb.failures = 0, 0, 0
+	cb.openedAt = time.Time{}
+	cb.generation++
+}
@@ -0,0 +1,97 @@
+package monerium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// allowOrFail returns a ticket of an attempt cb must let through.
+func allowOrFail(t *testing.T, cb *circuitBreaker) breakerTicket {
+	t.Helper()
+
+	ticket, err := cb.allow()
+	if err != nil {
+		t.Fatalf("allow: %v", err)
+	}
+	return ticket
+}
+
+func TestCircuitBreakerTripsAndRecovers(t *testing.T) {
+	cb := newCircuitBreaker(CircuitBreakerSettings{Window: 2, FailureRate: 1, Cooldown: 10 * time.Millisecond})
+
+	cb.record(allowOrFail(t, cb), true, false)
+	cb.record(allowOrFail(t, cb), true, false)
+	if _, err := cb.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow after trip = %v, want ErrCircuitOpen", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	probe := allowOrFail(t, cb)
+	if _, err := cb.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow while probing = %v, want ErrCircuitOpen", err)
+	}
+	cb.record(probe, true, false)
+	if _, err := cb.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow after failed probe = %v, want ErrCircuitOpen", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	cb.record(allowOrFail(t, cb), false, false)
+	allowOrFail(t, cb)
+}
+
+func TestCircuitBreakerIgnoresStaleOutcomeWhileProbing(t *testing.T) {
+	cb := newCircuitBreaker(CircuitBreakerSettings{Window: 2, FailureRate: 1, Cooldown: 10 * time.Millisecond})
+
+	stale := allowOrFail(t, cb)
+	cb.record(allowOrFail(t, cb), true, false)
+	cb.record(allowOrFail(t, cb), true, false)
+
+	time.Sleep(10 * time.Millisecond)
+	probe := allowOrFail(t, cb)
+	cb.record(stale, false, false)
+	if _, err := cb.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow after stale success = %v, want ErrCircuitOpen", err)
+	}
+
+	cb.record(probe, false, false)
+	allowOrFail(t, cb)
+}
+
+func TestCircuitBreakerDefaults(t *testing.T) {
+	cb := newCircuitBreaker(CircuitBreakerSettings{Window: 3})
+
+	for i := 0; i < 3; i++ {
+		cb.record(allowOrFail(t, cb), false, false)
+	}
+	allowOrFail(t, cb)
+
+	if cb.settings.FailureRate <= 0 || cb.settings.Cooldown <= 0 {
+		t.Errorf("settings = %+v, want positive FailureRate and Cooldown", cb.settings)
+	}
+}
+
+func TestClientCircuitBreakerShortCircuitsCalls(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}), WithCircuitBreaker(CircuitBreakerSettings{Window: 2, FailureRate: 1, Cooldown: time.Minute}))
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetBalances(ctx); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d: err = %v, want API error", i+1, err)
+		}
+	}
+	if _, err := c.GetBalances(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("server got %d calls, want 2", n)
+	}
+}
@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/go-querystring/query"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 	"nhooyr.io/websocket"
@@ -25,10 +29,19 @@ const (
 	ProductionTokenURL     = "https://api.monerium.app/auth/token"
 )
 
+const correlationIDHeader = "X-Correlation-Id"
+
 // NewClient initializes a new API client.
 // baseURL and wsURL should point to corresponding urls for Sandbox or Production environments.
+// They may include a path prefix, e.g. https://gateway.example.com/monerium, when the API is
+// served behind a gateway; a trailing slash is ignored.
 // AuthConfig is used for passing data related to OAuth2 ClientCredentials flow.
 // Client behavior can be tweaked via ClientOption.
+//
+// REST and websocket connections, as well as token requests, go through the same transport,
+// which honors HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+// If ctx carries an *http.Client under oauth2.HTTPClient, token requests are made with it
+// and its transport is used for REST and websocket connections, unless WithTransport is given.
 func NewClient(ctx context.Context, baseURL, wsURL string, auth *AuthConfig, opts ...ClientOption) *Client {
 	conf := &clientcredentials.Config{
 		ClientID:     auth.ClientID,
@@ -36,20 +49,87 @@ func NewClient(ctx context.Context, baseURL, wsURL string, auth *AuthConfig, opt
 		TokenURL:     auth.TokenURL,
 	}
 
+	return newClient(ctx, baseURL, wsURL, conf.TokenSource, opts...)
+}
+
+// NewClientWithRefreshToken initializes a new API client authenticated with a refresh token,
+// e.g. obtained in OAuth2 Authorization Code flow and persisted for a long-lived session.
+// Access tokens are minted from the refresh token when needed.
+// See NewClient for description of the remaining parameters.
+func NewClientWithRefreshToken(ctx context.Context, baseURL, wsURL string, auth *RefreshTokenAuthConfig, opts ...ClientOption) *Client {
+	conf := &oauth2.Config{
+		ClientID:     auth.ClientID,
+		ClientSecret: auth.ClientSecret,
+		Endpoint:     oauth2.Endpoint{TokenURL: auth.TokenURL},
+	}
+	tokenSource := func(ctx context.Context) oauth2.TokenSource {
+		ts := conf.TokenSource(ctx, &oauth2.Token{RefreshToken: auth.RefreshToken})
+		if auth.OnRefreshTokenRotated == nil {
+			return ts
+		}
+		return &rotationNotifyingTokenSource{
+			src:          ts,
+			refreshToken: auth.RefreshToken,
+			onRotated:    auth.OnRefreshTokenRotated,
+		}
+	}
+
+	return newClient(ctx, baseURL, wsURL, tokenSource, opts...)
+}
+
+// newClient initializes a new API client with token source built by tokenSource.
+func newClient(ctx context.Context, baseURL, wsURL string, tokenSource func(context.Context) oauth2.TokenSource, opts ...ClientOption) *Client {
 	cli := &Client{
-		baseURL:     baseURL,
-		wsURL:       wsURL,
-		httpClient:  conf.Client(ctx),
-		tokenSource: conf.TokenSource(ctx),
-		notifyTick:  500 * time.Millisecond,
+		baseURL:            strings.TrimRight(baseURL, "/"),
+		wsURL:              strings.TrimRight(wsURL, "/"),
+		notifyTick:         500 * time.Millisecond,
+		pollInterval:       5 * time.Second,
+		dupWindow:          10 * time.Minute,
+		concurrency:        4,
+		logger:             nopLogger{},
+		rateLimitThreshold: 1,
+		messageTolerance:   DefaultMessageTimestampTolerance,
+		closed:             make(chan struct{}),
 	}
 	for _, o := range opts {
 		o(cli)
 	}
 
+	// An *http.Client passed in ctx under oauth2.HTTPClient keeps serving token requests,
+	// and its transport becomes the default one, unless WithTransport overrides it.
+	hc, _ := ctx.Value(oauth2.HTTPClient).(*http.Client)
+	if cli.transport != nil || hc == nil {
+		if cli.transport == nil {
+			cli.transport = newDefaultTransport()
+			cli.ownsTransport = true
+		}
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: cli.transport})
+	} else {
+		cli.transport = http.DefaultTransport
+		if hc.Transport != nil {
+			cli.transport = hc.Transport
+		}
+	}
+
+	cli.tokenSource = tokenSource(ctx)
+	if cli.onTokenRefresh != nil {
+		cli.tokenSource = &refreshNotifyingTokenSource{src: cli.tokenSource, onRefresh: cli.onTokenRefresh}
+	}
+	cli.httpClient = oauth2.NewClient(ctx, cli.tokenSource)
+
 	return cli
 }
 
+// newDefaultTransport returns a transport configured as http.DefaultTransport, but with a connection pool
+// of its own, so that closing a Client doesn't affect connections of others.
+func newDefaultTransport() http.RoundTripper {
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		return t.Clone()
+	}
+
+	return http.DefaultTransport
+}
+
 // ClientOption represents an configurable option to Client.
 type ClientOption func(*Client)
 
@@ -60,13 +140,334 @@ func WithNotifyTick(d time.Duration) ClientOption {
 	}
 }
 
+// WithWSReadTimeout sets how long a websocket connection may stay silent.
+// When no message arrives in time, the connection is considered stalled and is re-established,
+// which detects half-open connections. By default, reads wait indefinitely.
+func WithWSReadTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.wsReadTimeout = d
+	}
+}
+
+// WithWSDialTimeout bounds how long dialing a websocket connection, including the handshake, may take.
+// By default, dialing is bounded by the context of the call only.
+func WithWSDialTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.wsDialTimeout = d
+	}
+}
+
+// WithWSPingInterval enables checking liveness of websocket connections with pings sent every d.
+// When a pong doesn't arrive within d plus the tick set by WithNotifyTick, the connection is considered
+// dead: the failure is reported as an OrderResult and the connection is re-established. It detects
+// connections that stopped responding even while the server has nothing to send. By default, no pings are sent.
+func WithWSPingInterval(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.wsPingInterval = d
+	}
+}
+
+// WithNotificationsTransport sets transport used by SubscribeOrders.
+func WithNotificationsTransport(t NotificationsTransport) ClientOption {
+	return func(c *Client) {
+		c.notifyTransport = t
+	}
+}
+
+// WithPollInterval sets interval of polling orders when SubscribeOrders doesn't use websocket.
+// It is also the default interval of WaitForOrders and WaitForOrderState, and must be positive.
+func WithPollInterval(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.pollInterval = d
+	}
+}
+
+// WithTransport sets the transport used for REST calls, token requests and websocket connections.
+// It allows sharing a connection pool between several clients, e.g. with different credentials;
+// every client still wraps rt with its own OAuth2 layer, so tokens are never shared.
+// rt must be safe for concurrent use by multiple goroutines, as *http.Transport is.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		c.transport = rt
+	}
+}
+
+// WithRetry sets the default RetryPolicy of all calls.
+// By default, failed calls are not retried.
+// The policy can be overridden for a single call with WithRetryPolicy.
+func WithRetry(p RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// WithConcurrency sets maximum number of concurrent requests made by calls spanning many resources,
+// e.g. GetAllOrders.
+func WithConcurrency(n int) ClientOption {
+	return func(c *Client) {
+		c.concurrency = n
+	}
+}
+
+// WithDuplicateOrderWindow sets how far back FindExistingOrder looks for matching orders.
+func WithDuplicateOrderWindow(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.dupWindow = d
+	}
+}
+
+// WithTokenCache enables caching of GetTokens results for ttl.
+// Tokens change rarely, so lookups like GetTokenBySymbol don't have to call the API every time.
+// By default, tokens are not cached.
+func WithTokenCache(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.tokens.ttl = ttl
+	}
+}
+
+// WithOrderCache enables caching of orders retrieved by GetOrder once they reach a terminal state,
+// see OrderState.IsTerminal. Such orders don't change anymore, so loops checking an order's state
+// don't hit the API again after it's processed or rejected. ttl bounds how long an order is cached.
+// By default, orders are not cached.
+func WithOrderCache(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.orders.ttl = ttl
+	}
+}
+
+// WithDefaultCounterpartCountry sets the ISO 3166-1 alpha-2 code, e.g. "DE", sent by PlaceOrder
+// as the counterpart's country when the request leaves it empty. A country set in the request takes precedence.
+// code is upper-cased; if it's not a valid code, PlaceOrder fails for requests relying on it.
+func WithDefaultCounterpartCountry(code string) ClientOption {
+	return func(c *Client) {
+		c.defaultCountry = strings.ToUpper(code)
+	}
+}
+
+// WithLanguage sets the Accept-Language header of REST calls to tag, e.g. "fr" or "fr-FR, en;q=0.8",
+// asking for error messages in that language. Languages Monerium doesn't translate to fall back to
+// the server default, English; see the API documentation for the supported ones.
+// By default, the header isn't sent.
+func WithLanguage(tag string) ClientOption {
+	return func(c *Client) {
+		c.language = tag
+	}
+}
+
+// WithAPIVersion pins the version of the API used by REST calls, e.g. "v2", by requesting
+// the versioned media type application/vnd.monerium.api-<v>+json in the Accept header.
+// It protects the integration from changes of the server's default version.
+// By default, no Accept header is sent and the server's default version is used.
+func WithAPIVersion(v string) ClientOption {
+	return func(c *Client) {
+		c.apiVersion = v
+	}
+}
+
+// WithDefaultProfile makes calls taking a profile ID, e.g. GetProfile or GetBalancesForProfile,
+// use the default profile of the authenticated user when the ID is empty.
+// The default profile is looked up with GetAuthContext once, on the first such call, and cached
+// for the lifetime of the Client; a failed lookup is retried on the next call.
+// Calls fail with ErrNoDefaultProfile if the user has no default profile.
+func WithDefaultProfile() ClientOption {
+	return func(c *Client) {
+		c.defaultProfile.enabled = true
+	}
+}
+
+// WithMemoTrim normalizes memos of orders read from the API, for setups where memos are stored
+// with metadata around the reference set in PlaceOrderRequest.Memo, e.g. by a gateway.
+// prefix and suffix are removed from Order.Memo, each only if present, by GetOrders, GetOrder and order
+// notifications (see TrimMemo). GetOrdersRequest.Memo is extended with them before filtering,
+// so that filtering by the original reference matches. By default, memos are left as they are.
+func WithMemoTrim(prefix, suffix string) ClientOption {
+	return func(c *Client) {
+		c.memoTrim = memoTrim{prefix: prefix, suffix: suffix}
+	}
+}
+
+// WithLogger sets logger of the Client's diagnostics, e.g. retried attempts.
+// By default, nothing is logged.
+func WithLogger(l Logger) ClientOption {
+	return func(c *Client) {
+		if l == nil {
+			l = nopLogger{}
+		}
+		c.logger = l
+	}
+}
+
+// WithTokenRefreshCallback sets fn to be called whenever the Client obtains a new access token,
+// e.g. to monitor refresh cadence. fn receives only the expiry of the token, zero if unknown.
+// fn is called synchronously by the call needing the token, so it should return quickly.
+func WithTokenRefreshCallback(fn func(expiry time.Time)) ClientOption {
+	return func(c *Client) {
+		c.onTokenRefresh = fn
+	}
+}
+
+// WithMessageCheck makes PlaceOrder, and so RedeemAndWait, check PlaceOrderRequest.Message before
+// sending the request: it must have the format of BuildOrderMessage, match currency, amount and counterpart
+// of the request, and have a timestamp within DefaultMessageTimestampTolerance of the local clock,
+// see WithMessageTimestampTolerance.
+// The check is client-side only and catches mistakes early, e.g. a message signed for another amount;
+// Monerium remains the authority on which messages it accepts. By default, messages aren't checked.
+func WithMessageCheck() ClientOption {
+	return func(c *Client) {
+		c.messageCheck = true
+	}
+}
+
+// WithMessageTimestampTolerance sets how far the timestamp of an order message may be from the local clock
+// for the check enabled by WithMessageCheck, e.g. to allow for clock skew or slow signing with hardware wallets.
+// A non-positive d disables the timestamp check, leaving the rest of the message check in place.
+// By default, DefaultMessageTimestampTolerance is used.
+func WithMessageTimestampTolerance(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.messageTolerance = d
+	}
+}
+
+// WithRateLimitThreshold makes WaitForRateLimit, and so UploadFiles, wait for the rate limit window to reset
+// once fewer than n requests remain in it, keeping the rest for other callers sharing the limit,
+// e.g. other goroutines or processes using the same credentials. n below 1 is treated as 1.
+// By default, n is 1, i.e. waiting starts once no requests remain.
+func WithRateLimitThreshold(n int) ClientOption {
+	return func(c *Client) {
+		c.rateLimitThreshold = n
+	}
+}
+
 // Client represents a new Monerium API client.
+//
+// Client is safe for concurrent use by multiple goroutines, so a single Client should be shared
+// rather than created per call. Its configuration is fixed once NewClient returns, and all state
+// changing afterwards (e.g. the cached access token) is guarded by a mutex.
 type Client struct {
 	baseURL     string
 	wsURL       string
+	transport   http.RoundTripper
 	httpClient  *http.Client
 	tokenSource oauth2.TokenSource
 	notifyTick  time.Duration
+	dupWindow   time.Duration
+
+	notifyTransport    NotificationsTransport
+	pollInterval       time.Duration
+	wsReadTimeout      time.Duration
+	wsDialTimeout      time.Duration
+	wsPingInterval     time.Duration
+	concurrency        int
+	retry              RetryPolicy
+	tokens             tokenCache
+	orders             orderCache
+	breaker            *circuitBreaker
+	defaultCountry     string
+	language           string
+	onTokenRefresh     func(expiry time.Time)
+	apiVersion         string
+	defaultProfile     defaultProfile
+	authContext        authContextCache
+	logger             Logger
+	rateLimiter        rateLimiter
+	rateLimitThreshold int
+	memoTrim           memoTrim
+	messageCheck       bool
+	messageTolerance   time.Duration
+
+	// ownsTransport reports whether transport was created for the Client, rather than shared with others.
+	ownsTransport bool
+	closeOnce     sync.Once
+	closed        chan struct{}
+}
+
+// ErrClientClosed is returned by calls made after Client.Close.
+var ErrClientClosed = errors.New("client is closed")
+
+// Close stops all background workers of the Client, e.g. order notifications, closing their websockets,
+// and releases idle connections. Subscribers receive ErrClientClosed as the final result.
+// The Client is unusable afterwards, every call returns ErrClientClosed, also when it could be served from a cache.
+// Connections of a transport shared with other clients, set WithTransport or passed in the NewClient context,
+// are left open. Close is safe to call multiple times.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		if c.ownsTransport {
+			(&http.Client{Transport: c.transport}).CloseIdleConnections()
+		}
+	})
+
+	return nil
+}
+
+// isClosed reports whether Close was called.
+func (c *Client) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// withClose returns a copy of ctx which is also cancelled when the Client is closed.
+func (c *Client) withClose(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-c.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// doneErr returns the reason of ctx obtained from withClose being done.
+func (c *Client) doneErr(ctx context.Context) error {
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+
+	return ctx.Err()
+}
+
+// Environment represents a Monerium environment.
+type Environment string
+
+const (
+	EnvSandbox    Environment = "sandbox"
+	EnvProduction Environment = "production"
+	// EnvCustom represents any environment not recognized by its URL, e.g. a local proxy.
+	EnvCustom Environment = "custom"
+)
+
+// Environment reports which environment the Client's base URL belongs to.
+func (c *Client) Environment() Environment {
+	switch c.baseURL {
+	case SandboxBaseURL:
+		return EnvSandbox
+	case ProductionBaseURL:
+		return EnvProduction
+	default:
+		return EnvCustom
+	}
+}
+
+// TokenExpiry returns expiry time of the current access token.
+// A cached valid token is reused, so the call doesn't force a refresh;
+// a new token is requested only if there is none or the cached one has expired.
+func (c *Client) TokenExpiry(ctx context.Context) (time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return time.Time{}, err
+	}
+	tok, err := c.tokenSource.Token()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get auth token: %w", err)
+	}
+
+	return tok.Expiry, nil
 }
 
 // AuthConfig is used for passing data related to OAuth2 Client Credentials flow.
@@ -79,10 +480,88 @@ type AuthConfig struct {
 	TokenURL string
 }
 
+// RefreshTokenAuthConfig is used for passing data related to OAuth2 Refresh Token grant.
+type RefreshTokenAuthConfig struct {
+	// ClientID is the application's ID.
+	ClientID string
+	// ClientSecret is the application's secret, empty for public clients.
+	ClientSecret string
+	// TokenURL is the resource server's token endpoint URL.
+	TokenURL string
+	// RefreshToken is the stored refresh token used for minting access tokens.
+	RefreshToken string
+	// OnRefreshTokenRotated is called with the new refresh token whenever the server rotates it,
+	// so it can be persisted for the next session. It's optional.
+	OnRefreshTokenRotated func(refreshToken string)
+}
+
+// rotationNotifyingTokenSource calls onRotated whenever the refresh token of tokens from src changes.
+type rotationNotifyingTokenSource struct {
+	src       oauth2.TokenSource
+	onRotated func(refreshToken string)
+
+	mu           sync.Mutex
+	refreshToken string
+}
+
+// Token returns a token from the underlying token source and reports rotation of its refresh token.
+func (ts *rotationNotifyingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := ts.src.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if tok.RefreshToken != "" && tok.RefreshToken != ts.refreshToken {
+		ts.refreshToken = tok.RefreshToken
+		ts.onRotated(tok.RefreshToken)
+	}
+
+	return tok, nil
+}
+
+// refreshNotifyingTokenSource calls onRefresh whenever src returns a new token.
+// src is expected to reuse its token until it expires, as token sources of oauth2 configs do.
+type refreshNotifyingTokenSource struct {
+	src       oauth2.TokenSource
+	onRefresh func(expiry time.Time)
+
+	mu   sync.Mutex
+	last *oauth2.Token
+}
+
+// Token returns a token from the underlying token source and reports it if it's new.
+func (ts *refreshNotifyingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := ts.src.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if tok != ts.last {
+		ts.last = tok
+		ts.onRefresh(tok.Expiry)
+	}
+
+	return tok, nil
+}
+
 // dialWebsocket creates authorization header and dials websocket under path.
-func dialWebsocket(ctx context.Context, path string, tok *oauth2.Token) (*websocket.Conn, error) {
+// The connection uses the same transport as REST calls.
+func (c *Client) dialWebsocket(ctx context.Context, path string, tok *oauth2.Token) (*websocket.Conn, error) {
+	h := newAuthorizationHeaderFrom(tok)
+	setContextHeaders(ctx, h)
+
+	if c.wsDialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.wsDialTimeout)
+		defer cancel()
+	}
 	wc, _, err := websocket.Dial(ctx, path, &websocket.DialOptions{
-		HTTPHeader: newAuthorizationHeaderFrom(tok),
+		HTTPClient: &http.Client{Transport: c.transport},
+		HTTPHeader: h,
 	})
 	return wc, err
 }
@@ -96,61 +575,82 @@ func newAuthorizationHeaderFrom(tok *oauth2.Token) http.Header {
 	}
 }
 
-// get makes a HTTP GET request against path (base URL is taken from Client)
-// and returns response body (as bytes) and headers on success.
-func (c *Client) get(ctx context.Context, path string) ([]byte, error) {
-	r, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, http.NoBody)
+// newRequest creates a new HTTP request against path (base URL is taken from Client)
+// with headers derived from ctx.
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	r, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(r)
-	if err != nil {
-		return nil, err
+	setContextHeaders(ctx, r.Header)
+	if c.language != "" {
+		r.Header.Set("Accept-Language", c.language)
 	}
-	defer resp.Body.Close()
-	bs, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if c.apiVersion != "" {
+		r.Header.Set("Accept", fmt.Sprintf("application/vnd.monerium.api-%s+json", c.apiVersion))
+	}
+
+	return r, nil
+}
+
+// setContextHeaders sets headers carried by ctx, e.g. correlation ID, unless they are already set.
+func setContextHeaders(ctx context.Context, h http.Header) {
+	if id := CorrelationIDFromContext(ctx); id != "" && h.Get(correlationIDHeader) == "" {
+		h.Set(correlationIDHeader, id)
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, newErrorFrom(path, bs, resp.Header)
+}
+
+// get makes a HTTP GET request against path (base URL is taken from Client)
+// and returns response body (as bytes) and headers on success.
+// params holds query parameters, e.g. GetOrdersRequest; unless nil, it's validated if it implements Validator
+// and encoded as the query string of path. Requests identifying a resource by path, e.g. GetOrderRequest,
+// are validated by callers before path is built.
+func (c *Client) get(ctx context.Context, path string, params any) ([]byte, error) {
+	if params != nil {
+		if err := validate(params); err != nil {
+			return nil, err
+		}
+		v, err := query.Values(params)
+		if err != nil {
+			return nil, err
+		}
+		if qs := v.Encode(); qs != "" {
+			path += "?" + qs
+		}
 	}
 
-	return bs, nil
+	return c.do(ctx, &request{
+		method:   http.MethodGet,
+		path:     path,
+		statuses: []int{http.StatusOK},
+	})
 }
 
 // post makes a HTTP POST request with req against path (base URL is taken from Client)
 // and returns response body (as bytes) and headers on success.
-// req is expected to be 'marshallable' to JSON.
+// req is expected to be 'marshallable' to JSON, it's validated if it implements Validator.
 func (c *Client) post(ctx context.Context, path string, req any) ([]byte, error) {
-	rs, err := json.Marshal(req)
-	if err != nil {
+	if err := validate(req); err != nil {
 		return nil, err
 	}
-	r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(rs))
-	if err != nil {
-		return nil, err
-	}
-	resp, err := c.httpClient.Do(r)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	bs, err := io.ReadAll(resp.Body)
+	rs, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
-		return nil, newErrorFrom(path, bs, resp.Header)
-	}
 
-	return bs, nil
+	return c.do(ctx, &request{
+		method:   http.MethodPost,
+		path:     path,
+		body:     rs,
+		statuses: []int{http.StatusOK, http.StatusAccepted},
+	})
 }
 
 // upload makes a HTTP POST request with form against path (base URL is taken from Client)
 // and returns response body (as bytes) and headers on success.
 // content is a content of a file to be uploaded, represented by the filename.
-func (c *Client) upload(ctx context.Context, path string, filename string, content io.Reader) ([]byte, error) {
+// progress is optional, see UploadFileRequest.Progress.
+func (c *Client) upload(ctx context.Context, path string, filename string, content io.Reader, progress func(sent, total int64)) ([]byte, error) {
 	var buf bytes.Buffer
 	w := multipart.NewWriter(&buf)
 	fw, err := w.CreateFormFile("file", filename)
@@ -162,58 +662,190 @@ func (c *Client) upload(ctx context.Context, path string, filename string, conte
 	}
 	w.Close()
 
-	r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, &buf)
+	return c.do(ctx, &request{
+		method:      http.MethodPost,
+		path:        path,
+		body:        buf.Bytes(),
+		contentType: w.FormDataContentType(),
+		progress:    progress,
+		statuses:    []int{http.StatusOK},
+	})
+}
+
+// request describes a HTTP request made by do.
+type request struct {
+	method string
+	// path is relative to the base URL of the Client.
+	path string
+	// body and contentType are optional.
+	body        []byte
+	contentType string
+	// progress is optionally called as the body is being sent.
+	progress func(sent, total int64)
+	// statuses are response status codes treated as success.
+	statuses []int
+}
+
+// do makes a HTTP request, retrying it according to the retry policy,
+// and returns response body if response status is one of expected statuses.
+// Once ctx is done, do returns ctx.Err() as is, without wrapping it or making further attempts.
+func (c *Client) do(ctx context.Context, req *request) ([]byte, error) {
+	if c.isClosed() {
+		return nil, ErrClientClosed
+	}
+
+	p := c.retryPolicy(ctx)
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ticket, err := c.breaker.allow()
+		if err != nil {
+			if lastErr != nil {
+				return nil, fmt.Errorf("%w: %w", err, lastErr)
+			}
+			return nil, err
+		}
+		actx, cancel := attemptContext(ctx, p.MaxAttempts-attempt+1)
+		bs, retry, err := c.doOnce(actx, req)
+		cancel()
+		if err != nil && actx.Err() != nil && ctx.Err() == nil {
+			// The attempt ran out of its share of the deadline, the rest is left for retries.
+			retry = true
+		}
+		c.breaker.record(ticket, err != nil && retry, ctx.Err() != nil)
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err == nil || !retry || attempt >= p.MaxAttempts {
+			return bs, err
+		}
+		lastErr = err
+		d := p.backoff(attempt)
+		c.logger.Debugf("monerium: %s %s attempt %d failed due to %s, retrying in %s%s",
+			req.method, pathOnly(req.path), attempt, retryReason(err), d, logLabels(ctx))
+		if err := sleep(ctx, d); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// doOnce makes a single attempt of a HTTP request. It reports whether the failed request can be retried.
+func (c *Client) doOnce(ctx context.Context, req *request) ([]byte, bool, error) {
+	var rb io.Reader = http.NoBody
+	if req.body != nil {
+		rb = bytes.NewReader(req.body)
+		if req.progress != nil {
+			rb = &progressReader{r: rb, total: int64(len(req.body)), progress: req.progress}
+		}
+	}
+	r, err := c.newRequest(ctx, req.method, req.path, rb)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	if req.body != nil {
+		r.ContentLength = int64(len(req.body))
 	}
-	r.Header.Set("Content-Type", w.FormDataContentType())
+	if req.contentType != "" {
+		r.Header.Set("Content-Type", req.contentType)
+	}
+
 	resp, err := c.httpClient.Do(r)
 	if err != nil {
-		return nil, err
+		return nil, isRetryableError(ctx, err), err
 	}
 	defer resp.Body.Close()
+	c.rateLimiter.update(resp.StatusCode, resp.Header, time.Now())
+	captureResponseHeader(ctx, resp.Header)
 	bs, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, isRetryableError(ctx, err), err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, newErrorFrom(path, bs, resp.Header)
+	for _, s := range req.statuses {
+		if resp.StatusCode == s {
+			return bs, false, nil
+		}
 	}
 
-	return bs, nil
+	return nil, isRetryableStatus(resp.StatusCode), newErrorFrom(req.path, resp.StatusCode, bs, resp.Header)
 }
 
-// newErrorFrom creates a new client-facing error from call name, response body and headers.
-func newErrorFrom(callName string, body []byte, header http.Header) error {
-	var errResp errorResponse
-	if err := json.Unmarshal(body, &errResp); err != nil {
-		return err
-	}
+// progressReader reports number of bytes read from r to progress.
+type progressReader struct {
+	r        io.Reader
+	sent     int64
+	total    int64
+	progress func(sent, total int64)
+}
 
-	msg := fmt.Sprintf("%s call failed due to: %s", callName, errResp.Message)
-	if corrID, ok := header["X-Correlation-Id"]; ok {
-		errResp.CorrelationID = corrID[0]
-		msg = fmt.Sprintf("%s. CorrelationID: %s", msg, errResp.CorrelationID)
+// Read reads from the underlying reader and reports progress.
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	if n > 0 {
+		pr.sent += int64(n)
+		pr.progress(pr.sent, pr.total)
 	}
-	if errResp.Errors != nil {
-		msg = fmt.Sprintf("%s. Details: %s", msg, errResp.Errors)
+
+	return n, err
+}
+
+// Validator is implemented by requests which can check themselves before being sent.
+// Every request type of the SDK implements it. Request bodies are validated by post and query parameters by get,
+// other requests are validated before they're used to build the path of the call.
+type Validator interface {
+	Validate() error
+}
+
+var (
+	_ Validator = (*GetBalancesForProfileRequest)(nil)
+	_ Validator = (*PlaceOrderRequest)(nil)
+	_ Validator = (*GetOrderRequest)(nil)
+	_ Validator = (*GetOrdersRequest)(nil)
+	_ Validator = (*OrdersNotificationsRequest)(nil)
+	_ Validator = (*GetProfileRequest)(nil)
+	_ Validator = (*AddAddressToProfileRequest)(nil)
+	_ Validator = (*UploadFileRequest)(nil)
+)
+
+// validate validates req if it implements Validator.
+func validate(req any) error {
+	if v, ok := req.(Validator); ok {
+		return v.Validate()
 	}
 
-	return fmt.Errorf(msg)
+	return nil
 }
 
-// errorResponse represents error response and CorrelationID taken from 'X-Correlation-Id' header.
-// Details represents details about resource failure.
-// Errors represents a nested map of fields that failed validation.
-type errorResponse struct {
-	Code    int    `json:"code"`
-	Status  string `json:"status"`
-	Message string `json:"message"`
-	Details struct {
-		ID       string `json:"id"`
-		Method   string `json:"method"`
-		Resource string `json:"resource"`
-	} `json:"details"`
-	Errors        json.RawMessage `json:"errors"`
-	CorrelationID string
+// runConcurrently calls fn for every index in [0, n), running at most c.concurrency calls at once,
+// and returns errors aligned with indexes. Once ctx is done, fn isn't called for the remaining indexes,
+// their error is ctx.Err().
+func (c *Client) runConcurrently(ctx context.Context, n int, fn func(i int) error) []error {
+	limit := c.concurrency
+	if limit < 1 {
+		limit = 1
+	}
+
+	errs := make([]error, n)
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+
+	return errs
 }
@@ -0,0 +1,515 @@
+package monerium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"nhooyr.io/websocket"
+
+	"github.com/monerium/go-sdk/monerium/moneriumtest"
+)
+
+// staticTokenSource returns a token source handing out a fixed access token.
+func staticTokenSource(context.Context) oauth2.TokenSource {
+	return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"})
+}
+
+// newTestClient starts a server with h and returns a Client pointed at it.
+func newTestClient(t *testing.T, h http.Handler, opts ...ClientOption) *Client {
+	t.Helper()
+
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	return newClient(context.Background(), srv.URL, "ws"+strings.TrimPrefix(srv.URL, "http"), staticTokenSource, opts...)
+}
+
+// writeJSON responds with v encoded as JSON.
+func writeJSON(t *testing.T, w http.ResponseWriter, v interface{}) {
+	t.Helper()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Errorf("encode response: %v", err)
+	}
+}
+
+func TestNewClientHonorsContextHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth/token":
+			writeJSON(t, w, map[string]interface{}{"access_token": "token", "token_type": "Bearer", "expires_in": 3600})
+		case "/balances":
+			writeJSON(t, w, []*ProfileBalance{})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	rt := &moneriumtest.RecordingTransport{}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: rt})
+	c := NewClient(ctx, srv.URL, "", &AuthConfig{ClientID: "id", ClientSecret: "secret", TokenURL: srv.URL + "/auth/token"})
+
+	if _, err := c.GetBalances(context.Background()); err != nil {
+		t.Fatalf("GetBalances: %v", err)
+	}
+
+	var paths []string
+	for _, call := range rt.Calls() {
+		paths = append(paths, call.URL.Path)
+	}
+	got := strings.Join(paths, ",")
+	if want := "/auth/token,/balances"; got != want {
+		t.Errorf("requests through context client = %q, want %q", got, want)
+	}
+}
+
+func TestWithTransportOverridesContextHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth/token":
+			writeJSON(t, w, map[string]interface{}{"access_token": "token", "token_type": "Bearer", "expires_in": 3600})
+		case "/balances":
+			writeJSON(t, w, []*ProfileBalance{})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctxRT, rt := &moneriumtest.RecordingTransport{}, &moneriumtest.RecordingTransport{}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: ctxRT})
+	c := NewClient(ctx, srv.URL, "", &AuthConfig{ClientID: "id", ClientSecret: "secret", TokenURL: srv.URL + "/auth/token"},
+		WithTransport(rt))
+
+	if _, err := c.GetBalances(context.Background()); err != nil {
+		t.Fatalf("GetBalances: %v", err)
+	}
+
+	var paths []string
+	for _, call := range rt.Calls() {
+		paths = append(paths, call.URL.Path)
+	}
+	got := strings.Join(paths, ",")
+	if want := "/auth/token,/balances"; got != want {
+		t.Errorf("requests through WithTransport = %q, want %q", got, want)
+	}
+	if n := len(ctxRT.Calls()); n != 0 {
+		t.Errorf("got %d requests through context client, want 0", n)
+	}
+}
+
+func TestTransportProxyIsUsedByRESTAndWebsocket(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		hosts []string
+	)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts = append(hosts, r.Host+r.URL.Path)
+		mu.Unlock()
+
+		if r.URL.Path == "/auth/token" {
+			writeJSON(t, w, map[string]interface{}{"access_token": "token", "token_type": "Bearer", "expires_in": 3600})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/balances" {
+			writeJSON(t, w, []*ProfileBalance{})
+			return
+		}
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.Close(websocket.StatusNormalClosure, "")
+	}))
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := &http.Transport{Proxy: http.ProxyURL(proxyURL)}
+	defer tr.CloseIdleConnections()
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: tr})
+	auth := &AuthConfig{ClientID: "id", ClientSecret: "secret", TokenURL: "http://api.monerium.test/auth/token"}
+	c := NewClient(ctx, "http://api.monerium.test", "ws://api.monerium.test", auth)
+
+	if _, err := c.GetBalances(context.Background()); err != nil {
+		t.Fatalf("GetBalances: %v", err)
+	}
+	conn, err := c.dialWebsocket(context.Background(), c.wsURL+"/profiles/p/orders", &oauth2.Token{AccessToken: "token"})
+	if err != nil {
+		t.Fatalf("dialWebsocket: %v", err)
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+
+	mu.Lock()
+	defer mu.Unlock()
+	got := strings.Join(hosts, ",")
+	if want := "api.monerium.test/auth/token,api.monerium.test/balances,api.monerium.test/profiles/p/orders"; got != want {
+		t.Errorf("requests through proxy = %q, want %q", got, want)
+	}
+}
+
+func TestClientConcurrentCalls(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", "50")
+		w.Header().Set("X-RateLimit-Reset", "10")
+		w.Header().Set(correlationIDHeader, "corr")
+		switch r.URL.Path {
+		case "/balances":
+			writeJSON(t, w, []*ProfileBalance{{ProfileID: "p1", Address: "0x1", Chain: ChainEthereum, Network: NetworkMainnet}})
+		case "/orders":
+			writeJSON(t, w, []*Order{{ID: "o1", Memo: "[rent]", Meta: OrderMeta{State: OrderStateProcessed}}})
+		case "/orders/o1":
+			writeJSON(t, w, &Order{ID: "o1", Memo: "[rent]", Meta: OrderMeta{State: OrderStateProcessed}})
+		case "/tokens":
+			writeJSON(t, w, []*Token{{Currency: "eur", Symbol: "EURe", Decimals: 18}})
+		default:
+			http.NotFound(w, r)
+		}
+	}), WithTokenCache(time.Minute), WithOrderCache(time.Minute), WithMemoTrim("[", "]"),
+		WithCircuitBreaker(CircuitBreakerSettings{Window: 10}))
+
+	const goroutines, calls = 32, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines*calls)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			ctx := WithLabels(context.Background(), map[string]string{"goroutine": fmt.Sprint(g)})
+			for i := 0; i < calls; i++ {
+				var h http.Header
+				var err error
+				switch (g + i) % 4 {
+				case 0:
+					_, err = c.GetBalances(ctx, WithResponseHeader(&h))
+				case 1:
+					var os []*Order
+					os, err = c.GetOrders(ctx, &GetOrdersRequest{Memo: "rent"})
+					if err == nil && os[0].Memo != "rent" {
+						err = fmt.Errorf("memo = %q, want %q", os[0].Memo, "rent")
+					}
+				case 2:
+					_, err = c.GetOrder(ctx, &GetOrderRequest{OrderID: "o1"})
+				case 3:
+					_, err = c.GetTokens(ctx)
+				}
+				c.RateLimit()
+				errs <- err
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTokenExpiryReusesCachedToken(t *testing.T) {
+	var tokenRequests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&tokenRequests, 1)
+		writeJSON(t, w, map[string]interface{}{"access_token": "token", "token_type": "Bearer", "expires_in": 3600})
+	}))
+	defer srv.Close()
+
+	var refreshed []time.Time
+	c := NewClient(context.Background(), srv.URL, "", &AuthConfig{ClientID: "id", ClientSecret: "secret", TokenURL: srv.URL},
+		WithTokenRefreshCallback(func(expiry time.Time) { refreshed = append(refreshed, expiry) }))
+
+	before := time.Now()
+	exp, err := c.TokenExpiry(context.Background())
+	if err != nil {
+		t.Fatalf("TokenExpiry: %v", err)
+	}
+	if exp.Before(before.Add(59*time.Minute)) || exp.After(time.Now().Add(time.Hour)) {
+		t.Errorf("expiry = %s, want an hour from now", exp)
+	}
+
+	again, err := c.TokenExpiry(context.Background())
+	if err != nil {
+		t.Fatalf("TokenExpiry: %v", err)
+	}
+	if !again.Equal(exp) {
+		t.Errorf("second expiry = %s, want cached %s", again, exp)
+	}
+	if n := atomic.LoadInt32(&tokenRequests); n != 1 {
+		t.Errorf("got %d token requests, want 1", n)
+	}
+	if len(refreshed) != 1 || !refreshed[0].Equal(exp) {
+		t.Errorf("refresh callback got %v, want [%s]", refreshed, exp)
+	}
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/token" {
+			writeJSON(t, w, []*Token{})
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse token request: %v", err)
+		}
+		if gt := r.PostForm.Get("grant_type"); gt != "refresh_token" {
+			t.Errorf("grant_type = %q, want refresh_token", gt)
+		}
+		mu.Lock()
+		received = append(received, r.PostForm.Get("refresh_token"))
+		n := len(received)
+		mu.Unlock()
+		// Tokens expiring within oauth2's expiry delta are refreshed on every use.
+		writeJSON(t, w, map[string]interface{}{
+			"access_token":  fmt.Sprintf("access%d", n),
+			"token_type":    "Bearer",
+			"refresh_token": fmt.Sprintf("refresh%d", n+1),
+			"expires_in":    1,
+		})
+	}))
+	defer srv.Close()
+
+	var rotated []string
+	c := NewClientWithRefreshToken(context.Background(), srv.URL, "", &RefreshTokenAuthConfig{
+		ClientID:              "id",
+		TokenURL:              srv.URL + "/token",
+		RefreshToken:          "refresh1",
+		OnRefreshTokenRotated: func(rt string) { rotated = append(rotated, rt) },
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetTokens(context.Background()); err != nil {
+			t.Fatalf("GetTokens: %v", err)
+		}
+	}
+
+	if want := []string{"refresh1", "refresh2"}; fmt.Sprint(received) != fmt.Sprint(want) {
+		t.Errorf("server got refresh tokens %v, want %v", received, want)
+	}
+	if want := []string{"refresh2", "refresh3"}; fmt.Sprint(rotated) != fmt.Sprint(want) {
+		t.Errorf("rotated to %v, want %v", rotated, want)
+	}
+}
+
+func TestBaseURLPathPrefix(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		writeJSON(t, w, []*Token{})
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{srv.URL, "/tokens"},
+		{srv.URL + "/", "/tokens"},
+		{srv.URL + "/monerium/v1", "/monerium/v1/tokens"},
+		{srv.URL + "/monerium/v1/", "/monerium/v1/tokens"},
+		{srv.URL + "/monerium/v1//", "/monerium/v1/tokens"},
+	}
+	for _, tt := range tests {
+		c := newClient(context.Background(), tt.baseURL, "", staticTokenSource)
+		if _, err := c.GetTokens(context.Background()); err != nil {
+			t.Fatalf("%s: GetTokens: %v", tt.baseURL, err)
+		}
+		mu.Lock()
+		if len(paths) != 1 || paths[0] != tt.want {
+			t.Errorf("%s: requested %v, want [%s]", tt.baseURL, paths, tt.want)
+		}
+		paths = nil
+		mu.Unlock()
+	}
+}
+
+func TestWithLanguageSetsAcceptLanguage(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		langs []string
+	)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		langs = append(langs, r.Header.Get("Accept-Language"))
+		mu.Unlock()
+		writeJSON(t, w, []*Token{})
+	})
+	ctx := context.Background()
+
+	if _, err := newTestClient(t, h, WithLanguage("fr-FR, en;q=0.8")).GetTokens(ctx); err != nil {
+		t.Fatalf("GetTokens: %v", err)
+	}
+	if _, err := newTestClient(t, h).GetTokens(ctx); err != nil {
+		t.Fatalf("GetTokens: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(langs) != 2 || langs[0] != "fr-FR, en;q=0.8" || langs[1] != "" {
+		t.Errorf("Accept-Language = %q, want the tag and then unset by default", langs)
+	}
+}
+
+func TestRequestsAreValidatedBeforeSending(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+	}))
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"GetOrders", func() error {
+			_, err := c.GetOrders(ctx, &GetOrdersRequest{State: "approved"})
+			return err
+		}},
+		{"GetOrder", func() error {
+			_, err := c.GetOrder(ctx, &GetOrderRequest{})
+			return err
+		}},
+		{"GetProfile", func() error {
+			_, err := c.GetProfile(ctx, nil)
+			return err
+		}},
+		{"OrdersNotifications", func() error {
+			return c.OrdersNotifications(ctx, &OrdersNotificationsRequest{ProfileID: "p1/../orders"}, make(chan *OrderResult))
+		}},
+		{"SubscribeOrders", func() error {
+			return c.SubscribeOrders(ctx, &OrdersNotificationsRequest{ProfileID: "p1?x=1"}, make(chan *OrderResult))
+		}},
+		{"PlaceOrder", func() error {
+			_, err := c.PlaceOrder(ctx, nil)
+			return err
+		}},
+		{"AddAddressToProfile", func() error {
+			_, err := c.AddAddressToProfile(ctx, nil)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+}
+
+func TestGetEncodesQueryParams(t *testing.T) {
+	var rawQuery string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawQuery = r.URL.RawQuery
+		writeJSON(t, w, []*Order{})
+	}))
+
+	if _, err := c.GetOrders(context.Background(), nil); err != nil {
+		t.Fatalf("GetOrders: %v", err)
+	}
+	if rawQuery != "" {
+		t.Errorf("query without filters = %q, want none", rawQuery)
+	}
+
+	if _, err := c.GetOrders(context.Background(), &GetOrdersRequest{State: OrderStateProcessed, ProfileID: "p1"}); err != nil {
+		t.Fatalf("GetOrders: %v", err)
+	}
+	q, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Get("state") != "processed" || q.Get("profile") != "p1" {
+		t.Errorf("query = %q, want state and profile filters", rawQuery)
+	}
+}
+
+// idleClosingTransport counts calls of CloseIdleConnections.
+type idleClosingTransport struct {
+	http.RoundTripper
+	closed int32
+}
+
+func (t *idleClosingTransport) CloseIdleConnections() {
+	atomic.AddInt32(&t.closed, 1)
+}
+
+func TestCloseKeepsSharedTransportOpen(t *testing.T) {
+	rt := &idleClosingTransport{RoundTripper: http.DefaultTransport}
+	c := newClient(context.Background(), "http://localhost", "", staticTokenSource, WithTransport(rt))
+	other := newClient(context.Background(), "http://localhost", "", staticTokenSource, WithTransport(rt))
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if n := atomic.LoadInt32(&rt.closed); n != 0 {
+		t.Errorf("shared transport idle connections closed %d times, want 0", n)
+	}
+	if other.isClosed() {
+		t.Error("closing a client closed another one sharing its transport")
+	}
+
+	own := newClient(context.Background(), "http://localhost", "", staticTokenSource)
+	if !own.ownsTransport || own.transport == http.DefaultTransport {
+		t.Error("client without WithTransport should own a transport of its own")
+	}
+}
+
+func TestCloseStopsCachedCalls(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tokens":
+			writeJSON(t, w, []*Token{{Currency: "eur", Symbol: "EURe"}})
+		case "/orders/o1":
+			writeJSON(t, w, &Order{ID: "o1", Meta: OrderMeta{State: OrderStateProcessed}})
+		default:
+			http.NotFound(w, r)
+		}
+	}), WithTokenCache(time.Minute), WithOrderCache(time.Minute))
+	ctx := context.Background()
+
+	if _, err := c.GetTokens(ctx); err != nil {
+		t.Fatalf("GetTokens: %v", err)
+	}
+	if _, err := c.GetOrder(ctx, &GetOrderRequest{OrderID: "o1"}); err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	c.Close()
+
+	if _, err := c.GetTokens(ctx); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("GetTokens after Close = %v, want ErrClientClosed", err)
+	}
+	if _, err := c.GetOrder(ctx, &GetOrderRequest{OrderID: "o1"}); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("GetOrder after Close = %v, want ErrClientClosed", err)
+	}
+}
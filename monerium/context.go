@@ -0,0 +1,54 @@
+package monerium
+
+import "context"
+
+type labelsKey struct{}
+
+// WithLabels returns a copy of ctx carrying labels, e.g. tenant or environment,
+// for slicing logs, metrics and traces by business dimensions.
+// Labels set on a parent context are kept unless overwritten by the same key.
+//
+// Labels are never sent to Monerium. They travel with the context of every outgoing
+// *http.Request, so they can be read with LabelsFromContext(req.Context()),
+// e.g. in a custom http.RoundTripper.
+func WithLabels(ctx context.Context, labels map[string]string) context.Context {
+	ls := make(map[string]string, len(labels))
+	for k, v := range LabelsFromContext(ctx) {
+		ls[k] = v
+	}
+	for k, v := range labels {
+		ls[k] = v
+	}
+
+	return context.WithValue(ctx, labelsKey{}, ls)
+}
+
+// LabelsFromContext returns a copy of labels set with WithLabels, or nil if there are none.
+func LabelsFromContext(ctx context.Context) map[string]string {
+	ls, ok := ctx.Value(labelsKey{}).(map[string]string)
+	if !ok {
+		return nil
+	}
+
+	cp := make(map[string]string, len(ls))
+	for k, v := range ls {
+		cp[k] = v
+	}
+
+	return cp
+}
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a copy of ctx carrying id, which is sent to Monerium
+// in the X-Correlation-Id header of every request made with the context.
+// It allows tracing a call across systems with an ID generated upstream.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationIDFromContext returns the correlation ID set with WithCorrelationID, or an empty string.
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
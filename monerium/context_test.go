@@ -0,0 +1,72 @@
+package monerium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"golang.org/x/oauth2"
+	"nhooyr.io/websocket"
+)
+
+func TestCorrelationIDIsSent(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		ids = map[string]string{}
+	)
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ids[r.URL.Path] = r.Header.Get(correlationIDHeader)
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/balances":
+			writeJSON(t, w, []*ProfileBalance{})
+		case "/orders":
+			conn, err := websocket.Accept(w, r, nil)
+			if err != nil {
+				return
+			}
+			conn.Close(websocket.StatusNormalClosure, "")
+		default:
+			w.Header().Set(correlationIDHeader, r.Header.Get(correlationIDHeader))
+			http.Error(w, `{"code":404,"message":"not found"}`, http.StatusNotFound)
+		}
+	}))
+	ctx := WithCorrelationID(context.Background(), "upstream-1")
+
+	if _, err := c.GetBalances(ctx); err != nil {
+		t.Fatalf("GetBalances: %v", err)
+	}
+	conn, err := c.dialWebsocket(ctx, c.wsURL+"/orders", &oauth2.Token{AccessToken: "token"})
+	if err != nil {
+		t.Fatalf("dialWebsocket: %v", err)
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+
+	_, err = c.GetOrder(ctx, &GetOrderRequest{OrderID: "missing"})
+	var errResp *ErrorResponse
+	if !errors.As(err, &errResp) || errResp.CorrelationID != "upstream-1" {
+		t.Errorf("GetOrder error = %v, want correlation ID echoed back", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, path := range []string{"/balances", "/orders", "/orders/missing"} {
+		if ids[path] != "upstream-1" {
+			t.Errorf("%s sent correlation ID %q, want %q", path, ids[path], "upstream-1")
+		}
+	}
+}
+
+func TestSetContextHeadersKeepsExistingCorrelationID(t *testing.T) {
+	h := http.Header{}
+	h.Set(correlationIDHeader, "set-by-caller")
+
+	setContextHeaders(WithCorrelationID(context.Background(), "from-context"), h)
+	if got := h.Get(correlationIDHeader); got != "set-by-caller" {
+		t.Errorf("correlation ID = %q, want %q", got, "set-by-caller")
+	}
+}
@@ -0,0 +1,206 @@
+package monerium
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrNotFound is returned by lookups, e.g. GetTokenBySymbol, when no resource matches.
+var ErrNotFound = errors.New("not found")
+
+// maxRawErrorBody is the maximum length of a non-JSON response body kept in ErrorResponse.
+const maxRawErrorBody = 512
+
+// newErrorFrom creates a new client-facing error from call name, response status code, body and headers.
+// Bodies which aren't JSON, e.g. HTML pages of a gateway, are kept (truncated) as the message.
+// Code and Status missing from the body are taken from the response status.
+func newErrorFrom(callName string, status int, body []byte, header http.Header) error {
+	errResp := ErrorResponse{callName: callName}
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		errResp = ErrorResponse{
+			Code:     status,
+			Status:   http.StatusText(status),
+			Message:  truncateBody(body),
+			callName: callName,
+			raw:      true,
+		}
+	}
+	if errResp.Code == 0 {
+		errResp.Code = status
+	}
+	if errResp.Status == "" {
+		errResp.Status = http.StatusText(status)
+	}
+	errResp.CorrelationID = correlationIDFrom(header)
+
+	return &errResp
+}
+
+// ErrorResponse represents an error returned by Monerium API and CorrelationID taken from 'X-Correlation-Id' header.
+// Details represents details about resource failure.
+// Errors represents a nested map of fields that failed validation, see FieldErrors.
+// Code and Status missing from the response body are taken from the response status. When the body
+// isn't JSON, e.g. an HTML page of a gateway, Message holds the body, truncated.
+type ErrorResponse struct {
+	Code    int    `json:"code"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Details struct {
+		ID       string `json:"id"`
+		Method   string `json:"method"`
+		Resource string `json:"resource"`
+	} `json:"details"`
+	Errors        json.RawMessage `json:"errors"`
+	CorrelationID string          `json:"-"`
+
+	callName string
+	// raw reports whether the response body wasn't JSON, Message holds the body then.
+	raw bool
+}
+
+// Error implements error interface.
+func (e *ErrorResponse) Error() string {
+	msg := fmt.Sprintf("%s call failed due to: %s", e.callName, e.Message)
+	if e.raw || e.Message == "" {
+		// Without a message from the API, the status is the best description of the failure.
+		msg = fmt.Sprintf("%s call failed with %d %s", e.callName, e.Code, e.Status)
+		if e.Message != "" {
+			msg = fmt.Sprintf("%s: %s", msg, e.Message)
+		}
+	}
+	if e.CorrelationID != "" {
+		msg = fmt.Sprintf("%s. CorrelationID: %s", msg, e.CorrelationID)
+	}
+	if e.hasErrors() {
+		msg = fmt.Sprintf("%s. Details: %s", msg, e.Errors)
+	}
+
+	return msg
+}
+
+// correlationIDFrom returns the correlation ID from header, whatever the case of its name, e.g. X-Correlation-ID.
+// header.Get covers canonicalized names, which is how net/http parses responses; names of headers
+// set directly on the map, e.g. by a custom transport, are compared case-insensitively.
+func correlationIDFrom(header http.Header) string {
+	if id := header.Get(correlationIDHeader); id != "" {
+		return id
+	}
+	for k, v := range header {
+		if strings.EqualFold(k, correlationIDHeader) && len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+
+	return ""
+}
+
+// truncateBody returns body as a trimmed string, cut to maxRawErrorBody bytes.
+func truncateBody(body []byte) string {
+	s := strings.TrimSpace(string(body))
+	if len(s) <= maxRawErrorBody {
+		return s
+	}
+	n := maxRawErrorBody
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n] + "..."
+}
+
+// FieldErrors returns validation failures of request fields.
+// Malformed Errors result in empty FieldErrors.
+func (e *ErrorResponse) FieldErrors() FieldErrors {
+	fe := make(FieldErrors)
+	if !e.hasErrors() {
+		return fe
+	}
+
+	var v any
+	if err := json.Unmarshal(e.Errors, &v); err != nil {
+		return fe
+	}
+	fe.add("", v)
+
+	return fe
+}
+
+// IsSupportingDocumentRequired reports whether err is an ErrorResponse rejecting an order
+// because it lacks a supporting document, see PlaceOrderRequest.SupportingDocumentID.
+// The API has no dedicated error code for it, so the check relies on the failure
+// being reported for the supportingDocumentId field or mentioned in the message.
+func IsSupportingDocumentRequired(err error) bool {
+	var errResp *ErrorResponse
+	if !errors.As(err, &errResp) {
+		return false
+	}
+	if len(errResp.FieldErrors().FieldError("supportingDocumentId")) > 0 {
+		return true
+	}
+
+	return strings.Contains(strings.ToLower(errResp.Message), "supporting document")
+}
+
+// hasErrors reports whether the response contains field validation errors.
+func (e *ErrorResponse) hasErrors() bool {
+	return len(e.Errors) > 0 && string(e.Errors) != "null"
+}
+
+// FieldErrors maps JSON paths of request fields, e.g. "counterpart.identifier.iban", to their validation failures.
+// Paths are the same whether the server reports errors under flat dotted keys or as nested objects.
+// Elements of arrays are addressed by index, e.g. "accounts.0.chain".
+type FieldErrors map[string][]string
+
+// FieldError returns validation failures of the field under JSON path, e.g. "counterpart.identifier.iban".
+func (fe FieldErrors) FieldError(path string) []string {
+	return fe[path]
+}
+
+// Paths returns sorted JSON paths of all fields that failed validation.
+func (fe FieldErrors) Paths() []string {
+	ps := make([]string, 0, len(fe))
+	for p := range fe {
+		ps = append(ps, p)
+	}
+	sort.Strings(ps)
+
+	return ps
+}
+
+// add adds failures found in v under path, descending into nested objects and arrays.
+func (fe FieldErrors) add(path string, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, vv := range v {
+			fe.add(joinPath(path, k), vv)
+		}
+	case []any:
+		for i, vv := range v {
+			if s, ok := vv.(string); ok {
+				fe[path] = append(fe[path], s)
+				continue
+			}
+			fe.add(joinPath(path, strconv.Itoa(i)), vv)
+		}
+	case string:
+		fe[path] = append(fe[path], v)
+	case nil:
+	default:
+		fe[path] = append(fe[path], fmt.Sprint(v))
+	}
+}
+
+// joinPath joins JSON path prefix with key.
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
+}
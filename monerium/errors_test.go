@@ -0,0 +1,143 @@
+package monerium
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestFieldErrorsOfNestedPayload(t *testing.T) {
+	body := []byte(`{
+		"code": 400,
+		"status": "Bad Request",
+		"message": "Validation errors",
+		"errors": {
+			"counterpart.identifier.iban": ["invalid"],
+			"counterpart": {
+				"details": {
+					"firstName": ["required", "too short"],
+					"address": {"lines": [{"street": "required"}]}
+				}
+			},
+			"amount": "must be positive"
+		}
+	}`)
+
+	err := newErrorFrom("PlaceOrder", http.StatusBadRequest, body, http.Header{})
+	var errResp *ErrorResponse
+	if !errors.As(err, &errResp) {
+		t.Fatalf("error %T is not *ErrorResponse", err)
+	}
+	fe := errResp.FieldErrors()
+
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"counterpart.identifier.iban", []string{"invalid"}},
+		{"counterpart.details.firstName", []string{"required", "too short"}},
+		{"counterpart.details.address.lines.0.street", []string{"required"}},
+		{"amount", []string{"must be positive"}},
+		{"memo", nil},
+	}
+	for _, tt := range tests {
+		if got := fe.FieldError(tt.path); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("FieldError(%q) = %v, want %v", tt.path, got, tt.expected)
+		}
+	}
+
+	paths := []string{
+		"amount",
+		"counterpart.details.address.lines.0.street",
+		"counterpart.details.firstName",
+		"counterpart.identifier.iban",
+	}
+	if got := fe.Paths(); !reflect.DeepEqual(got, paths) {
+		t.Errorf("Paths() = %v, want %v", got, paths)
+	}
+}
+
+func TestFieldErrorsWithoutErrors(t *testing.T) {
+	err := newErrorFrom("PlaceOrder", http.StatusBadRequest, []byte(`{"message":"bad"}`), http.Header{})
+	var errResp *ErrorResponse
+	if !errors.As(err, &errResp) {
+		t.Fatalf("error %T is not *ErrorResponse", err)
+	}
+	if fe := errResp.FieldErrors(); len(fe) != 0 {
+		t.Errorf("FieldErrors() = %v, want none", fe)
+	}
+}
+
+func TestNewErrorFromFillsMissingStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		code     int
+		status   string
+		expected string
+	}{
+		{"JSON without code", `{"details":{"id":"x"}}`, 503, "Service Unavailable", "GetBalances call failed with 503 Service Unavailable"},
+		{"JSON with message only", `{"message":"busy"}`, 503, "Service Unavailable", "GetBalances call failed due to: busy"},
+		{"JSON with code", `{"code":400,"status":"Bad Request","message":"bad"}`, 400, "Bad Request", "GetBalances call failed due to: bad"},
+		{"HTML", `<html>oops</html>`, 502, "Bad Gateway", "GetBalances call failed with 502 Bad Gateway: <html>oops</html>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newErrorFrom("GetBalances", tt.code, []byte(tt.body), http.Header{})
+			var errResp *ErrorResponse
+			if !errors.As(err, &errResp) {
+				t.Fatalf("error %T is not *ErrorResponse", err)
+			}
+			if errResp.Code != tt.code || errResp.Status != tt.status {
+				t.Errorf("Code, Status = %d, %q, want %d, %q", errResp.Code, errResp.Status, tt.code, tt.status)
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("Error() = %q, want %q", err.Error(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsSupportingDocumentRequired(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{
+			name: "field error",
+			body: `{"code":422,"status":"Unprocessable Entity","message":"Validation errors","errors":{"supportingDocumentId":["Supporting document is required for orders of 15000 EUR or more"]}}`,
+			want: true,
+		},
+		{
+			name: "message",
+			body: `{"code":400,"status":"Bad Request","message":"A supporting document is required for this order"}`,
+			want: true,
+		},
+		{
+			name: "other field error",
+			body: `{"code":422,"status":"Unprocessable Entity","message":"Validation errors","errors":{"amount":["must be positive"]}}`,
+		},
+		{
+			name: "other message",
+			body: `{"code":400,"status":"Bad Request","message":"Insufficient funds"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("placing order: %w", newErrorFrom("PlaceOrder", http.StatusBadRequest, []byte(tt.body), http.Header{}))
+			if got := IsSupportingDocumentRequired(err); got != tt.want {
+				t.Errorf("IsSupportingDocumentRequired = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if IsSupportingDocumentRequired(errors.New("supporting document is required")) {
+		t.Error("non-API error classified as supporting document required")
+	}
+	if IsSupportingDocumentRequired(nil) {
+		t.Error("nil error classified as supporting document required")
+	}
+}
@@ -3,6 +3,7 @@ package monerium
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"time"
 )
@@ -10,9 +11,13 @@ import (
 // UploadFile accepts request with filename and content of the file to be uploaded via generic file upload endpoint.
 // UploadFile can be used e.g. for uploading supporting documents for large redeem orders.
 func (c *Client) UploadFile(ctx context.Context, req *UploadFileRequest) (*File, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
 	path := "/files"
 
-	bs, err := c.upload(ctx, path, req.Filename, req.Content)
+	bs, err := c.upload(ctx, path, req.Filename, req.Content, req.Progress)
 	if err != nil {
 		return nil, err
 	}
@@ -24,10 +29,55 @@ func (c *Client) UploadFile(ctx context.Context, req *UploadFileRequest) (*File,
 	return &o, nil
 }
 
+// UploadFiles uploads files of reqs, see UploadFile, running at most as many uploads at once
+// as set by WithConcurrency. Before every upload it waits for the rate limit window to reset
+// if too few requests remain, see WaitForRateLimit.
+//
+// Files and errors are aligned with reqs: for every index either the file or the error is set.
+// Once ctx is done, files not uploaded yet fail with its error.
+func (c *Client) UploadFiles(ctx context.Context, reqs []*UploadFileRequest) ([]*File, []error) {
+	fs := make([]*File, len(reqs))
+	errs := c.runConcurrently(ctx, len(reqs), func(i int) error {
+		if err := c.WaitForRateLimit(ctx); err != nil {
+			return err
+		}
+		f, err := c.UploadFile(ctx, reqs[i])
+		if err != nil {
+			return err
+		}
+		fs[i] = f
+
+		return nil
+	})
+
+	return fs, errs
+}
+
 // UploadFileRequest contains filename and content of the file to be uploaded.
+//
+// Progress is optionally called with the number of bytes sent so far, as the request body is being sent.
+// total is the size of the whole request body, which is slightly bigger than the file itself.
+// Progress is called from the goroutine sending the request,
+// and starts over from zero when the upload is retried.
 type UploadFileRequest struct {
 	Filename string
 	Content  io.Reader
+	Progress func(sent, total int64)
+}
+
+// Validate checks UploadFileRequest.
+func (r *UploadFileRequest) Validate() error {
+	if r == nil {
+		return errors.New("UploadFileRequest is required")
+	}
+	if r.Filename == "" {
+		return errors.New("empty filename")
+	}
+	if r.Content == nil {
+		return errors.New("file content is missing")
+	}
+
+	return nil
 }
 
 // File represents a file that was successfully uploaded.
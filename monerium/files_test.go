@@ -0,0 +1,82 @@
+package monerium
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFileDecodesTimestamps(t *testing.T) {
+	// A file as sent by Monerium after upload.
+	payload := `{
+		"id": "3ad8bb7f-fe5b-11ed-a434-525f1bbe0e9e",
+		"name": "invoice.pdf",
+		"type": "application/pdf",
+		"size": 70438,
+		"hash": "2f4d5c4b3a2e1f0d9c8b7a6e5d4c3b2a1f0e9d8c7b6a5e4d3c2b1a0f9e8d7c6b",
+		"meta": {
+			"uploadedBy": "a7e6ea3e-fe5a-11ed-a434-525f1bbe0e9e",
+			"createdAt": "2023-05-29T12:51:12.528Z",
+			"updatedAt": "2023-05-29T12:51:12.528Z"
+		}
+	}`
+	var f File
+	if err := json.Unmarshal([]byte(payload), &f); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	want := time.Date(2023, 5, 29, 12, 51, 12, 528e6, time.UTC)
+	if f.Meta == nil || !f.Meta.CreatedAt.Equal(want) || !f.Meta.UpdatedAt.Equal(want) {
+		t.Errorf("meta = %+v, want created and updated at %s", f.Meta, want)
+	}
+}
+
+func TestUploadFileReportsProgress(t *testing.T) {
+	var received int64
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, err := io.Copy(io.Discard, r.Body)
+		if err != nil {
+			t.Errorf("read body: %v", err)
+		}
+		received = n
+		writeJSON(t, w, &File{ID: "f1", Name: "doc.pdf"})
+	}))
+
+	var sent, totals []int64
+	f, err := c.UploadFile(context.Background(), &UploadFileRequest{
+		Filename: "doc.pdf",
+		Content:  bytes.NewReader(bytes.Repeat([]byte("x"), 1<<20)),
+		Progress: func(s, total int64) {
+			sent = append(sent, s)
+			totals = append(totals, total)
+		},
+	})
+	if err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+	if f.ID != "f1" {
+		t.Errorf("file = %+v", f)
+	}
+
+	if len(sent) < 2 {
+		t.Fatalf("progress called %d times, want several calls for a large file", len(sent))
+	}
+	for i := 1; i < len(sent); i++ {
+		if sent[i] <= sent[i-1] {
+			t.Fatalf("progress not increasing: %v", sent)
+		}
+	}
+	total := totals[0]
+	for _, tt := range totals {
+		if tt != total {
+			t.Fatalf("total changed during upload: %v", totals)
+		}
+	}
+	if total <= 1<<20 || sent[len(sent)-1] != total || received != total {
+		t.Errorf("last progress %d of %d, server received %d", sent[len(sent)-1], total, received)
+	}
+}
@@ -0,0 +1,75 @@
+package monerium
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LedgerRecord contains fields of an Order commonly booked in accounting ledgers, see Order.LedgerRecord.
+type LedgerRecord struct {
+	OrderID   string
+	ProfileID string
+	AccountID string
+	Address   string
+	Kind      OrderKind
+	State     OrderState
+
+	Amount   Amount
+	Currency Currency
+
+	// CounterpartIBAN is normalized, e.g. "GR1601101250000000012300695"; it's empty for SCAN counterparts,
+	// which are identified by CounterpartSortCode and CounterpartAccountNumber instead.
+	CounterpartIBAN          string
+	CounterpartSortCode      string
+	CounterpartAccountNumber string
+	CounterpartName          string
+	CounterpartCountry       string
+	Memo                     string
+
+	// Timestamps are zero until the order reaches the corresponding state.
+	PlacedAt    time.Time
+	ApprovedAt  time.Time
+	ProcessedAt time.Time
+	RejectedAt  time.Time
+	// RejectedReason is set for rejected orders only.
+	RejectedReason string
+}
+
+// LedgerRecord maps the order to a LedgerRecord. It fails if Amount is malformed.
+func (o *Order) LedgerRecord() (LedgerRecord, error) {
+	a, err := ParseAmount(o.Amount)
+	if err != nil {
+		return LedgerRecord{}, fmt.Errorf("order %s: %w", o.ID, err)
+	}
+
+	cp := o.Counterpart
+	lr := LedgerRecord{
+		OrderID:   o.ID,
+		ProfileID: o.Profile,
+		AccountID: o.AccountID,
+		Address:   o.Address,
+		Kind:      o.Kind,
+		State:     o.Meta.State,
+
+		Amount:   a,
+		Currency: o.Currency,
+
+		CounterpartIBAN:          normalizeIBAN(cp.Identifier.IBAN),
+		CounterpartSortCode:      cp.Identifier.SortCode,
+		CounterpartAccountNumber: cp.Identifier.AccountNumber,
+		CounterpartName:          strings.TrimSpace(cp.Details.FirstName + " " + cp.Details.LastName),
+		CounterpartCountry:       cp.Details.Country,
+		Memo:                     o.Memo,
+
+		PlacedAt:    o.Meta.PlacedAt,
+		ApprovedAt:  o.Meta.ApprovedAt,
+		ProcessedAt: o.Meta.ProcessedAt,
+		RejectedAt:  o.Meta.RejectedAt,
+	}
+	if o.Meta.State == OrderStateRejected {
+		lr.RejectedReason = o.RejectedReason
+	}
+
+	return lr, nil
+}
@@ -0,0 +1,68 @@
+package monerium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Logger logs diagnostics of the Client, see WithLogger. It's satisfied by many logging libraries,
+// e.g. *zap.SugaredLogger. Messages never contain credentials, tokens or request bodies.
+// Messages about a call end with the labels of its context, see WithLabels, to correlate them with the caller.
+// Logger must be safe for concurrent use by multiple goroutines.
+type Logger interface {
+	// Debugf logs details useful when diagnosing problems, e.g. retried attempts.
+	Debugf(format string, args ...any)
+	// Warnf logs unexpected conditions the Client recovered from.
+	Warnf(format string, args ...any)
+}
+
+// nopLogger discards all messages.
+type nopLogger struct{}
+
+// Debugf implements Logger.
+func (nopLogger) Debugf(string, ...any) {}
+
+// Warnf implements Logger.
+func (nopLogger) Warnf(string, ...any) {}
+
+// pathOnly returns path without query, which may contain personal data, e.g. a memo or an address.
+func pathOnly(path string) string {
+	p, _, _ := strings.Cut(path, "?")
+
+	return p
+}
+
+// retryReason describes err of a failed attempt for logs: a status code of an API error,
+// or a network error stripped of the URL.
+func retryReason(err error) string {
+	var errResp *ErrorResponse
+	if errors.As(err, &errResp) {
+		return fmt.Sprintf("status %d", errResp.Code)
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err.Error()
+	}
+
+	return err.Error()
+}
+
+// logLabels formats labels of ctx, see WithLabels, to be appended to a log message.
+// It returns an empty string when there are no labels.
+func logLabels(ctx context.Context) string {
+	ls := LabelsFromContext(ctx)
+	if len(ls) == 0 {
+		return ""
+	}
+	kvs := make([]string, 0, len(ls))
+	for k, v := range ls {
+		kvs = append(kvs, k+"="+v)
+	}
+	sort.Strings(kvs)
+
+	return " [" + strings.Join(kvs, " ") + "]"
+}
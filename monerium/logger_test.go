@@ -0,0 +1,77 @@
+package monerium
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// recordingLogger records formatted messages.
+type recordingLogger struct {
+	mu     sync.Mutex
+	debugs []string
+	warns  []string
+}
+
+func (l *recordingLogger) Debugf(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.debugs = append(l.debugs, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Warnf(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warns = append(l.warns, fmt.Sprintf(format, args...))
+}
+
+func TestRetriedAttemptsAreLogged(t *testing.T) {
+	var calls int32
+	l := &recordingLogger{}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(t, w, []*Order{})
+	}), WithLogger(l), WithRetry(RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}))
+
+	ctx := WithLabels(context.Background(), map[string]string{"tenant": "acme", "job": "sync"})
+	if _, err := c.GetOrders(ctx, &GetOrdersRequest{Memo: "secret memo"}); err != nil {
+		t.Fatalf("GetOrders: %v", err)
+	}
+
+	expected := []string{
+		"monerium: GET /orders attempt 1 failed due to status 503, retrying in 1ms [job=sync tenant=acme]",
+		"monerium: GET /orders attempt 2 failed due to status 503, retrying in 2ms [job=sync tenant=acme]",
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if strings.Join(l.debugs, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("debug messages:\n%s\nwant:\n%s", strings.Join(l.debugs, "\n"), strings.Join(expected, "\n"))
+	}
+	for _, m := range l.debugs {
+		if strings.Contains(m, "secret") || strings.Contains(m, "token") {
+			t.Errorf("message %q leaks request data", m)
+		}
+	}
+}
+
+func TestSuccessfulCallIsNotLogged(t *testing.T) {
+	l := &recordingLogger{}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, []*Order{})
+	}), WithLogger(l), WithRetry(RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}))
+
+	if _, err := c.GetOrders(context.Background(), nil); err != nil {
+		t.Fatalf("GetOrders: %v", err)
+	}
+	if len(l.debugs) != 0 || len(l.warns) != 0 {
+		t.Errorf("unexpected messages: %v %v", l.debugs, l.warns)
+	}
+}
@@ -0,0 +1,94 @@
+package monerium
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxMemoLength is the maximum length of a SEPA remittance information, see PlaceOrderRequest.Memo.
+const MaxMemoLength = 140
+
+// memoFieldSeparator separates fields of a memo built by BuildStructuredMemo.
+const memoFieldSeparator = "/"
+
+// BuildStructuredMemo builds a memo of fields, e.g. an invoice number and a customer ID, separated by "/",
+// so that every memo of an integration has the same structure, e.g. "INV-2023-001/CUST42".
+// A creditor reference built by NewCreditorReference can be one of the fields.
+//
+// Fields may contain only characters of the SEPA character set (Latin letters, digits and
+// the characters - ? : ( ) . , ' + and space) and must not be empty. The memo can't be longer than MaxMemoLength.
+func BuildStructuredMemo(fields ...string) (string, error) {
+	if len(fields) == 0 {
+		return "", errors.New("no memo fields")
+	}
+	for i, f := range fields {
+		if f == "" {
+			return "", fmt.Errorf("memo field %d is empty", i)
+		}
+		for _, r := range f {
+			if !isSEPAChar(r) {
+				return "", fmt.Errorf("memo field %d: invalid character %q", i, r)
+			}
+		}
+	}
+
+	memo := strings.Join(fields, memoFieldSeparator)
+	if len(memo) > MaxMemoLength {
+		return "", fmt.Errorf("memo is %d characters long, at most %d allowed", len(memo), MaxMemoLength)
+	}
+
+	return memo, nil
+}
+
+// memoTrim strips prefix and suffix from memos of orders read from the API, see WithMemoTrim.
+type memoTrim struct {
+	prefix string
+	suffix string
+}
+
+// apply strips prefix and suffix from memos of os.
+func (t memoTrim) apply(os ...*Order) {
+	if t == (memoTrim{}) {
+		return
+	}
+	for _, o := range os {
+		if o != nil {
+			o.Memo = TrimMemo(o.Memo, t.prefix, t.suffix)
+		}
+	}
+}
+
+// TrimMemo returns memo without prefix and suffix, each removed only if present,
+// e.g. "GW:INV-1" without prefix "GW:" is "INV-1".
+func TrimMemo(memo, prefix, suffix string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(memo, prefix), suffix)
+}
+
+// isSEPAChar reports whether r belongs to the SEPA character set, excluding the memo field separator.
+func isSEPAChar(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	default:
+		return strings.ContainsRune("-?:().,'+ ", r)
+	}
+}
+
+// NewCreditorReference creates an ISO 11649 structured creditor reference ("RF" reference) of ref,
+// e.g. "RF18539007547034" of "539007547034", by computing its check digits.
+// ref may contain spaces and lower case letters, it's normalized; it must consist of 1 to 21
+// letters and digits.
+func NewCreditorReference(ref string) (string, error) {
+	ref = strings.ToUpper(strings.ReplaceAll(ref, " ", ""))
+	if len(ref) == 0 || len(ref) > 21 {
+		return "", fmt.Errorf("invalid creditor reference length: %q", ref)
+	}
+
+	rem, ok := mod97(ref + "RF00")
+	if !ok {
+		return "", fmt.Errorf("invalid creditor reference characters: %q", ref)
+	}
+
+	return fmt.Sprintf("RF%02d%s", 98-rem, ref), nil
+}
@@ -0,0 +1,127 @@
+package monerium
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBuildStructuredMemo(t *testing.T) {
+	rf, err := NewCreditorReference("539007547034")
+	if err != nil {
+		t.Fatalf("NewCreditorReference: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		fields  []string
+		want    string
+		wantErr bool
+	}{
+		{name: "single field", fields: []string{"INV-2023-001"}, want: "INV-2023-001"},
+		{name: "several fields", fields: []string{"INV-2023-001", "CUST42"}, want: "INV-2023-001/CUST42"},
+		{name: "creditor reference", fields: []string{rf, "CUST42"}, want: "RF18539007547034/CUST42"},
+		{name: "SEPA punctuation", fields: []string{"Invoice (1), 'Q3' +vat?"}, want: "Invoice (1), 'Q3' +vat?"},
+		{name: "max length", fields: []string{strings.Repeat("a", 70), strings.Repeat("b", 69)}, want: strings.Repeat("a", 70) + "/" + strings.Repeat("b", 69)},
+		{name: "too long", fields: []string{strings.Repeat("a", 70), strings.Repeat("b", 70)}, wantErr: true},
+		{name: "no fields", wantErr: true},
+		{name: "empty field", fields: []string{"INV-1", ""}, wantErr: true},
+		{name: "separator in field", fields: []string{"INV/1"}, wantErr: true},
+		{name: "non-SEPA character", fields: []string{"Rechnung für März"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildStructuredMemo(tt.fields...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildStructuredMemo = %q, %v, want error: %v", got, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("BuildStructuredMemo = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCreditorReference(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{ref: "539007547034", want: "RF18539007547034"},
+		{ref: "5390 0754 7034", want: "RF18539007547034"},
+		{ref: "G72UUR", want: "RF45G72UUR"},
+		{ref: "g72uur", want: "RF45G72UUR"},
+		{ref: "1", want: "RF741"},
+		{ref: "", wantErr: true},
+		{ref: strings.Repeat("1", 22), wantErr: true},
+		{ref: "INV-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NewCreditorReference(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("NewCreditorReference(%q) = %q, %v, want error: %v", tt.ref, got, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("NewCreditorReference(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestTrimMemo(t *testing.T) {
+	tests := []struct {
+		memo, prefix, suffix, want string
+	}{
+		{"GW:INV-1", "GW:", "", "INV-1"},
+		{"GW:INV-1#42", "GW:", "#42", "INV-1"},
+		{"INV-1#42", "GW:", "#42", "INV-1"},
+		{"INV-1", "GW:", "#42", "INV-1"},
+		{"GW:GW:INV-1", "GW:", "", "GW:INV-1"},
+		{"xGW:INV-1", "GW:", "", "xGW:INV-1"},
+	}
+	for _, tt := range tests {
+		if got := TrimMemo(tt.memo, tt.prefix, tt.suffix); got != tt.want {
+			t.Errorf("TrimMemo(%q, %q, %q) = %q, want %q", tt.memo, tt.prefix, tt.suffix, got, tt.want)
+		}
+	}
+}
+
+func TestWithMemoTrimRoundTripsReference(t *testing.T) {
+	var memoFilter string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orders":
+			memoFilter = r.URL.Query().Get("memo")
+			writeJSON(t, w, []*Order{{ID: "o1", Memo: "GW:INV-1"}, {ID: "o2", Memo: "plain"}})
+		case "/orders/o1":
+			writeJSON(t, w, &Order{ID: "o1", Memo: "GW:INV-1"})
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+	}), WithMemoTrim("GW:", ""))
+	ctx := context.Background()
+
+	req := &GetOrdersRequest{Memo: "INV-1"}
+	os, err := c.GetOrders(ctx, req)
+	if err != nil {
+		t.Fatalf("GetOrders: %v", err)
+	}
+	if memoFilter != "GW:INV-1" {
+		t.Errorf("memo filter = %q, want %q", memoFilter, "GW:INV-1")
+	}
+	if req.Memo != "INV-1" {
+		t.Errorf("req.Memo = %q, req was modified", req.Memo)
+	}
+	if len(os) != 2 || os[0].Memo != "INV-1" || os[1].Memo != "plain" {
+		t.Errorf("orders = %+v, want memos INV-1 and plain", os)
+	}
+
+	o, err := c.GetOrder(ctx, &GetOrderRequest{OrderID: "o1"})
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if o.Memo != "INV-1" {
+		t.Errorf("memo = %q, want INV-1", o.Memo)
+	}
+}
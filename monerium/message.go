@@ -0,0 +1,144 @@
+package monerium
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SignatureScheme represents a scheme used for signing PlaceOrderRequest.Message.
+type SignatureScheme string
+
+const (
+	// SignatureSchemePersonalSign is Ethereum personal_sign (EIP-191) signature
+	// of a plain text message built by BuildOrderMessage.
+	SignatureSchemePersonalSign SignatureScheme = "personal_sign"
+)
+
+// DefaultMessageTimestampTolerance is how far the timestamp of an order message may be from the local clock
+// for the check enabled by WithMessageCheck to accept it, unless changed by WithMessageTimestampTolerance.
+// Monerium accepts only recent messages, to prevent replaying signed ones; the tolerance is a client-side guard
+// against stale or mistimed messages, while the API enforces its own window.
+const DefaultMessageTimestampTolerance = 5 * time.Minute
+
+// BuildOrderMessage builds a message to be signed for placing a redeem order of amount in currency
+// to the counterpart identified by iban. The message is formatted as
+// `Send <CURRENCY> <AMOUNT> to <IBAN> at <TIMESTAMP>`, with at formatted as RFC3339.
+//
+// at defaults to the current time when zero; it can be set e.g. for reproducible tests.
+// Any timestamp is accepted here, see WithMessageCheck for checking it before placing the order.
+func BuildOrderMessage(currency Currency, amount, iban string, at time.Time) string {
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	return fmt.Sprintf("Send %s %s to %s at %s",
+		strings.ToUpper(string(currency)), amount, normalizeIBAN(iban), at.Format(time.RFC3339))
+}
+
+// orderMessageRegexp matches messages built by BuildOrderMessage.
+var orderMessageRegexp = regexp.MustCompile(`^Send ([A-Z]{3}) (\S+) to (.+) at (\S+)$`)
+
+// orderMessage represents parts of a message built by BuildOrderMessage.
+type orderMessage struct {
+	currency Currency
+	amount   string
+	target   string
+	at       time.Time
+}
+
+// parseOrderMessage parses message built by BuildOrderMessage.
+func parseOrderMessage(msg string) (*orderMessage, error) {
+	m := orderMessageRegexp.FindStringSubmatch(msg)
+	if m == nil {
+		return nil, errors.New("message doesn't match format: Send <CURRENCY> <AMOUNT> to <IBAN> at <TIMESTAMP>")
+	}
+	at, err := time.Parse(time.RFC3339, m[4])
+	if err != nil {
+		return nil, fmt.Errorf("message timestamp is not RFC3339: %w", err)
+	}
+
+	return &orderMessage{
+		currency: Currency(strings.ToLower(m[1])),
+		amount:   m[2],
+		target:   m[3],
+		at:       at,
+	}, nil
+}
+
+// validateMessage checks if Message has the format expected by SignatureScheme
+// and matches currency, amount and counterpart of the request. It returns the parsed message.
+func (r *PlaceOrderRequest) validateMessage() (*orderMessage, error) {
+	switch r.SignatureScheme {
+	case "", SignatureSchemePersonalSign:
+	default:
+		return nil, fmt.Errorf("unsupported signature scheme: %q", r.SignatureScheme)
+	}
+
+	m, err := parseOrderMessage(r.Message)
+	if err != nil {
+		return nil, err
+	}
+	if r.Currency != "" && m.currency != r.Currency {
+		return nil, fmt.Errorf("message currency %s doesn't match order currency %s", m.currency, r.Currency)
+	}
+	if !equalAmounts(m.amount, r.Amount) {
+		return nil, fmt.Errorf("message amount %s doesn't match order amount %s", m.amount, r.Amount)
+	}
+	if id := r.Counterpart.Identifier; id.Standard == IdentifierStandardIBAN && normalizeIBAN(m.target) != normalizeIBAN(id.IBAN) {
+		return nil, fmt.Errorf("message IBAN %s doesn't match counterpart IBAN %s", m.target, id.IBAN)
+	}
+
+	return m, nil
+}
+
+// validateTimestamp checks if the timestamp of m is within tolerance from now.
+// A non-positive tolerance accepts any timestamp.
+func (m *orderMessage) validateTimestamp(now time.Time, tolerance time.Duration) error {
+	if tolerance <= 0 {
+		return nil
+	}
+	if d := m.at.Sub(now); d > tolerance || d < -tolerance {
+		return fmt.Errorf("message timestamp %s is more than %s off the local clock (client-side check, see WithMessageTimestampTolerance)",
+			m.at.Format(time.RFC3339), tolerance)
+	}
+
+	return nil
+}
+
+// checkMessage checks Message of req before placing it if enabled by WithMessageCheck.
+// Requests without a counterpart are left to be rejected by validation.
+func (c *Client) checkMessage(req *PlaceOrderRequest, now time.Time) error {
+	if !c.messageCheck || req == nil || req.Counterpart == nil {
+		return nil
+	}
+	m, err := req.validateMessage()
+	if err != nil {
+		return fmt.Errorf("invalid message: %w", err)
+	}
+	if err := m.validateTimestamp(now, c.messageTolerance); err != nil {
+		return fmt.Errorf("invalid message: %w", err)
+	}
+
+	return nil
+}
+
+// DisplayMessage returns the authorization message to be shown to the user before signing.
+// It's exactly the Message sent with the request, returned only after verifying that it matches
+// currency, amount and counterpart of the request, so the user never authorizes something else
+// than what is displayed. Message is expected to be built by BuildOrderMessage.
+func (r *PlaceOrderRequest) DisplayMessage() (string, error) {
+	if r.Message == "" {
+		return "", errors.New("message is missing, build it with BuildOrderMessage")
+	}
+	if err := r.Counterpart.Validate(); err != nil {
+		return "", err
+	}
+	if _, err := r.validateMessage(); err != nil {
+		return "", fmt.Errorf("invalid message: %w", err)
+	}
+
+	return r.Message, nil
+}
@@ -0,0 +1,125 @@
+package monerium
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBuildOrderMessageWithFixedTimestamp(t *testing.T) {
+	at := time.Date(2023, 5, 29, 12, 45, 29, 0, time.UTC)
+
+	got := BuildOrderMessage(CurrencyEUR, "1.5", "gr16 0110 1250 0000 0001 2300 695", at)
+	if want := "Send EUR 1.5 to GR1601101250000000012300695 at 2023-05-29T12:45:29Z"; got != want {
+		t.Errorf("BuildOrderMessage = %q, want %q", got, want)
+	}
+}
+
+func TestCheckMessageTimestamp(t *testing.T) {
+	cp, err := NewCounterpart("GR1601101250000000012300695", "Jane", "Doe", "GR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		tolerance time.Duration
+		at        time.Time
+		wantErr   bool
+	}{
+		{"current", DefaultMessageTimestampTolerance, now, false},
+		{"slightly in the past", DefaultMessageTimestampTolerance, now.Add(-time.Minute), false},
+		{"too old", DefaultMessageTimestampTolerance, now.Add(-DefaultMessageTimestampTolerance - time.Minute), true},
+		{"too far in the future", DefaultMessageTimestampTolerance, now.Add(DefaultMessageTimestampTolerance + time.Minute), true},
+		{"old within custom tolerance", time.Hour, now.Add(-30 * time.Minute), false},
+		{"beyond custom tolerance", time.Minute, now.Add(-2 * time.Minute), true},
+		{"timestamp check disabled", 0, now.Add(-24 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClient(context.Background(), "", "", staticTokenSource, WithMessageCheck(), WithMessageTimestampTolerance(tt.tolerance))
+			req := &PlaceOrderRequest{
+				Amount:      "1.5",
+				Currency:    CurrencyEUR,
+				Counterpart: cp,
+				Message:     BuildOrderMessage(CurrencyEUR, "1.5", cp.Identifier.IBAN, tt.at),
+			}
+
+			err := c.checkMessage(req, now)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("checkMessage() = %v, want error: %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "client-side") {
+				t.Errorf("checkMessage() = %v, want client-side timestamp error", err)
+			}
+		})
+	}
+}
+
+func TestPlaceOrderChecksMessageOnlyWithOption(t *testing.T) {
+	cp, err := NewCounterpart("GR1601101250000000012300695", "Jane", "Doe", "GR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &PlaceOrderRequest{
+		Kind:        OrderKindRedeem,
+		Amount:      "1.5",
+		Currency:    CurrencyEUR,
+		Chain:       ChainEthereum,
+		Address:     "0x1",
+		Counterpart: cp,
+		// The message is signed for another amount.
+		Message:   BuildOrderMessage(CurrencyEUR, "15", cp.Identifier.IBAN, time.Now()),
+		Signature: "0xsig",
+	}
+
+	tests := []struct {
+		name     string
+		opts     []ClientOption
+		wantSent bool
+	}{
+		{"default", nil, true},
+		{"with message check", []ClientOption{WithMessageCheck()}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent int32
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&sent, 1)
+				writeJSON(t, w, Order{ID: "o1"})
+			}), tt.opts...)
+
+			_, err := c.PlaceOrder(context.Background(), req)
+			if gotSent := atomic.LoadInt32(&sent) > 0; gotSent != tt.wantSent {
+				t.Fatalf("request sent: %v, want %v (err: %v)", gotSent, tt.wantSent, err)
+			}
+			if tt.wantSent && err != nil {
+				t.Errorf("PlaceOrder: %v", err)
+			}
+			if !tt.wantSent && (err == nil || !strings.Contains(err.Error(), "amount")) {
+				t.Errorf("PlaceOrder = %v, want amount mismatch error", err)
+			}
+		})
+	}
+}
+
+func TestDisplayMessageAcceptsFixedTimestamp(t *testing.T) {
+	cp, err := NewCounterpart("GR1601101250000000012300695", "Jane", "Doe", "GR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := BuildOrderMessage(CurrencyEUR, "1", cp.Identifier.IBAN, time.Date(2023, 5, 29, 12, 45, 29, 0, time.UTC))
+	req := &PlaceOrderRequest{Amount: "1.00", Currency: CurrencyEUR, Counterpart: cp, Message: msg}
+
+	got, err := req.DisplayMessage()
+	if err != nil {
+		t.Fatalf("DisplayMessage: %v", err)
+	}
+	if got != msg {
+		t.Errorf("DisplayMessage = %q, want %q", got, msg)
+	}
+}
@@ -0,0 +1,181 @@
+// Package moneriumtest provides utilities for testing integrations of the monerium package.
+package moneriumtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces secrets in recorded calls.
+const Redacted = "REDACTED"
+
+// Call represents a recorded HTTP call. Response fields are empty if the call failed with Err.
+type Call struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+
+	StatusCode     int
+	ResponseHeader http.Header
+	ResponseBody   []byte
+	Err            error
+}
+
+// RecordingTransport is a http.RoundTripper which records calls made through it, e.g. to assert
+// which endpoints were called by a monerium.Client created with monerium.WithTransport.
+// Calls of the client's token requests and websocket handshakes are recorded too.
+//
+// Secrets are redacted by default: the Authorization header, secrets in token request forms,
+// e.g. client_secret, and tokens in token responses are replaced with Redacted.
+//
+// RecordingTransport is safe for concurrent use by multiple goroutines.
+type RecordingTransport struct {
+	// Transport makes the calls. It defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+	// RevealSecrets disables redaction of secrets.
+	RevealSecrets bool
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := Call{
+		Method: req.Method,
+		URL:    req.URL,
+		Header: req.Header.Clone(),
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		bs, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		c.Body = bs
+		// The request must not be modified, so the body is replaced on a copy.
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(bs))
+	}
+
+	resp, err := t.transport().RoundTrip(req)
+	if err != nil {
+		c.Err = err
+		t.record(c)
+		return nil, err
+	}
+
+	c.StatusCode = resp.StatusCode
+	c.ResponseHeader = resp.Header.Clone()
+	// Body of a websocket handshake is the connection itself, it can't be read up front.
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		bs, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			c.Err = err
+			t.record(c)
+			return nil, err
+		}
+		c.ResponseBody = bs
+		resp.Body = io.NopCloser(bytes.NewReader(bs))
+	}
+	t.record(c)
+
+	return resp, nil
+}
+
+// Calls returns calls recorded so far, in the order they completed.
+func (t *RecordingTransport) Calls() []Call {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]Call(nil), t.calls...)
+}
+
+// Reset forgets recorded calls.
+func (t *RecordingTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls = nil
+}
+
+// transport returns the underlying transport.
+func (t *RecordingTransport) transport() http.RoundTripper {
+	if t.Transport == nil {
+		return http.DefaultTransport
+	}
+
+	return t.Transport
+}
+
+// record records c, redacting its secrets unless they're revealed.
+func (t *RecordingTransport) record(c Call) {
+	if !t.RevealSecrets {
+		redact(&c)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls = append(t.calls, c)
+}
+
+// secretKeys are names of form fields and JSON keys holding secrets in OAuth2 requests and responses.
+var secretKeys = []string{"client_secret", "refresh_token", "access_token", "id_token", "code", "code_verifier", "password"}
+
+// redact replaces secrets in c with Redacted.
+func redact(c *Call) {
+	if c.Header.Get("Authorization") != "" {
+		c.Header.Set("Authorization", Redacted)
+	}
+	if strings.HasPrefix(c.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		c.Body = redactForm(c.Body)
+	}
+	if strings.HasPrefix(c.ResponseHeader.Get("Content-Type"), "application/json") {
+		c.ResponseBody = redactJSON(c.ResponseBody)
+	}
+}
+
+// redactForm replaces values of secretKeys in url-encoded form bs.
+func redactForm(bs []byte) []byte {
+	v, err := url.ParseQuery(string(bs))
+	if err != nil {
+		return []byte(Redacted)
+	}
+	for _, k := range secretKeys {
+		if v.Has(k) {
+			v.Set(k, Redacted)
+		}
+	}
+
+	return []byte(v.Encode())
+}
+
+// redactJSON replaces values of secretKeys in JSON object bs. Bodies other than objects are kept.
+func redactJSON(bs []byte) []byte {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(bs, &m); err != nil {
+		return bs
+	}
+	found := false
+	for _, k := range secretKeys {
+		if _, ok := m[k]; ok {
+			m[k] = json.RawMessage(`"` + Redacted + `"`)
+			found = true
+		}
+	}
+	if !found {
+		return bs
+	}
+	rbs, err := json.Marshal(m)
+	if err != nil {
+		return []byte(Redacted)
+	}
+
+	return rbs
+}
@@ -0,0 +1,153 @@
+package moneriumtest_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/monerium/go-sdk/monerium"
+	"github.com/monerium/go-sdk/monerium/moneriumtest"
+)
+
+// newServer starts a server issuing tokens and linking addresses, which checks it receives the request body.
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/auth/token":
+			io.WriteString(w, `{"access_token":"access-secret","token_type":"Bearer","expires_in":3600}`)
+		case "/tokens":
+			io.WriteString(w, `[]`)
+		case "/profiles/p1/addresses":
+			bs, _ := io.ReadAll(r.Body)
+			if !strings.Contains(string(bs), `"address":"0x1"`) {
+				t.Errorf("server got body %s", bs)
+			}
+			io.WriteString(w, `{"id":"p1"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// addAddress links an address with a client using rt.
+func addAddress(t *testing.T, srv *httptest.Server, rt *moneriumtest.RecordingTransport) {
+	t.Helper()
+
+	c := monerium.NewClient(context.Background(), srv.URL, "", &monerium.AuthConfig{
+		ClientID:     "id",
+		ClientSecret: "client-secret",
+		TokenURL:     srv.URL + "/auth/token",
+	}, monerium.WithTransport(rt))
+	defer c.Close()
+
+	_, err := c.AddAddressToProfile(context.Background(), &monerium.AddAddressToProfileRequest{
+		ProfileID: "p1",
+		Address:   "0x1",
+		Message:   "I hereby declare that I am the address owner.",
+		Signature: "0xsig",
+		Accounts:  []monerium.Account{{Currency: monerium.CurrencyEUR, Chain: monerium.ChainEthereum, Network: monerium.NetworkMainnet}},
+	})
+	if err != nil {
+		t.Fatalf("AddAddressToProfile: %v", err)
+	}
+}
+
+func TestRecordingTransportRecordsCalls(t *testing.T) {
+	srv := newServer(t)
+	rt := &moneriumtest.RecordingTransport{}
+	addAddress(t, srv, rt)
+
+	calls := rt.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("recorded %d calls, want 2", len(calls))
+	}
+	token, api := calls[0], calls[1]
+
+	if token.Method != http.MethodPost || token.URL.Path != "/auth/token" {
+		t.Errorf("first call = %s %s, want token request", token.Method, token.URL)
+	}
+	// Client credentials are sent with basic auth.
+	if got := token.Header.Get("Authorization"); got != moneriumtest.Redacted {
+		t.Errorf("token request Authorization = %q, want redacted", got)
+	}
+	if strings.Contains(string(token.ResponseBody), "access-secret") {
+		t.Errorf("access token not redacted: %s", token.ResponseBody)
+	}
+
+	if api.Method != http.MethodPost || api.URL.Path != "/profiles/p1/addresses" || api.StatusCode != http.StatusOK {
+		t.Errorf("second call = %s %s %d", api.Method, api.URL, api.StatusCode)
+	}
+	if got := api.Header.Get("Authorization"); got != moneriumtest.Redacted {
+		t.Errorf("Authorization = %q, want redacted", got)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(api.Body, &body); err != nil || body["address"] != "0x1" {
+		t.Errorf("recorded body = %s (%v)", api.Body, err)
+	}
+	if string(api.ResponseBody) != `{"id":"p1"}` {
+		t.Errorf("recorded response = %s", api.ResponseBody)
+	}
+
+	rt.Reset()
+	if n := len(rt.Calls()); n != 0 {
+		t.Errorf("%d calls after Reset", n)
+	}
+}
+
+func TestRecordingTransportRevealsSecrets(t *testing.T) {
+	srv := newServer(t)
+	rt := &moneriumtest.RecordingTransport{RevealSecrets: true}
+	addAddress(t, srv, rt)
+
+	calls := rt.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("recorded %d calls, want 2", len(calls))
+	}
+	if user, secret, ok := (&http.Request{Header: calls[0].Header}).BasicAuth(); !ok || user != "id" || secret != "client-secret" {
+		t.Errorf("token request Authorization = %q", calls[0].Header.Get("Authorization"))
+	}
+	if !strings.Contains(string(calls[0].ResponseBody), "access-secret") {
+		t.Errorf("access token redacted: %s", calls[0].ResponseBody)
+	}
+	if got := calls[1].Header.Get("Authorization"); got != "Bearer access-secret" {
+		t.Errorf("Authorization = %q", got)
+	}
+}
+
+func TestRecordingTransportRedactsTokenRequestForm(t *testing.T) {
+	srv := newServer(t)
+	rt := &moneriumtest.RecordingTransport{}
+	c := monerium.NewClientWithRefreshToken(context.Background(), srv.URL, "", &monerium.RefreshTokenAuthConfig{
+		ClientID:     "id",
+		TokenURL:     srv.URL + "/auth/token",
+		RefreshToken: "refresh-secret",
+	}, monerium.WithTransport(rt))
+	defer c.Close()
+
+	if _, err := c.GetTokens(context.Background()); err != nil {
+		t.Fatalf("GetTokens: %v", err)
+	}
+
+	calls := rt.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("recorded %d calls, want 2", len(calls))
+	}
+	form, err := url.ParseQuery(string(calls[0].Body))
+	if err != nil {
+		t.Fatalf("parse token request: %v", err)
+	}
+	if form.Get("grant_type") != "refresh_token" || form.Get("refresh_token") != moneriumtest.Redacted {
+		t.Errorf("token request form = %v, want redacted refresh token", form)
+	}
+}
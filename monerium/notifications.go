@@ -0,0 +1,179 @@
+package monerium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+// ErrWebsocketNotConfigured is returned by calls using websocket when the Client has no valid websocket URL.
+var ErrWebsocketNotConfigured = errors.New("websocket URL is not configured")
+
+var (
+	// errDialWebsocket marks failures of establishing websocket connection.
+	errDialWebsocket = errors.New("failed to dial websocket")
+	// errReadTimeout is returned when no websocket message arrives within the read timeout.
+	errReadTimeout = errors.New("websocket read timed out")
+	// errReadWebsocket marks failures of reading from websocket connection, after which it's unusable.
+	errReadWebsocket = errors.New("failed to read from websocket")
+)
+
+// validateWebsocketURL checks if websocket URL of the Client is a valid ws or wss URL.
+func (c *Client) validateWebsocketURL() error {
+	if c.wsURL == "" {
+		return ErrWebsocketNotConfigured
+	}
+	u, err := url.Parse(c.wsURL)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrWebsocketNotConfigured, err)
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" || u.Host == "" {
+		return fmt.Errorf("%w: %q is not a ws(s) URL", ErrWebsocketNotConfigured, c.wsURL)
+	}
+
+	return nil
+}
+
+// connectWebsocket gets an auth token and dials websocket under path.
+func (c *Client) connectWebsocket(ctx context.Context, path string) (*websocket.Conn, error) {
+	tok, err := c.tokenSource.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auth token: %w", err)
+	}
+
+	wc, err := c.dialWebsocket(ctx, path, tok)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errDialWebsocket, err)
+	}
+
+	return wc, nil
+}
+
+// watchLiveness returns a context of connection wc, which is canceled when wc is found dead by keepAlive.
+// Without WithWSPingInterval, liveness isn't checked.
+func (c *Client) watchLiveness(ctx context.Context, wc *websocket.Conn) (context.Context, context.CancelFunc) {
+	connCtx, connCancel := context.WithCancel(ctx)
+	if c.wsPingInterval > 0 {
+		go c.keepAlive(connCtx, connCancel, wc)
+	}
+
+	return connCtx, connCancel
+}
+
+// keepAlive pings wc every c.wsPingInterval until ctx is done or a pong doesn't arrive in time;
+// it cancels the connection then, see WithWSPingInterval. Pongs are received by a concurrent reader.
+func (c *Client) keepAlive(ctx context.Context, cancel context.CancelFunc, wc *websocket.Conn) {
+	t := time.NewTicker(c.wsPingInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			pctx, pcancel := context.WithTimeout(ctx, c.wsPingInterval+c.notifyTick)
+			err := wc.Ping(pctx)
+			pcancel()
+			if err != nil {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// NotificationsTransport represents a transport used for delivering order notifications.
+type NotificationsTransport int
+
+const (
+	// NotificationsTransportAuto uses websocket and falls back to polling when websocket can't be dialed.
+	NotificationsTransportAuto NotificationsTransport = iota
+	// NotificationsTransportWebsocket uses websocket only.
+	NotificationsTransportWebsocket
+	// NotificationsTransportPoll polls GetOrders with interval set by WithPollInterval.
+	NotificationsTransportPoll
+)
+
+// SubscribeOrders streams order updates over a channel, just like OrdersNotifications,
+// regardless of whether websockets are available in the environment.
+//
+// The transport is chosen via WithNotificationsTransport. By default websocket is used and,
+// if it isn't configured or can't be dialed, the client falls back to polling GetOrders.
+// When polling, orders existing at the time of subscribing are not emitted,
+// and an order is emitted again only when its state changes.
+func (c *Client) SubscribeOrders(ctx context.Context, req *OrdersNotificationsRequest, os chan<- *OrderResult) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	switch c.notifyTransport {
+	case NotificationsTransportWebsocket:
+		return c.ordersNotifications(ctx, req, os)
+	case NotificationsTransportPoll:
+		return c.pollOrders(ctx, req, os)
+	}
+
+	err := c.ordersNotifications(ctx, req, os)
+	if errors.Is(err, errDialWebsocket) || errors.Is(err, ErrWebsocketNotConfigured) {
+		return c.pollOrders(ctx, req, os)
+	}
+
+	return err
+}
+
+// pollOrders polls orders in the background and emits new orders and orders whose state changed.
+func (c *Client) pollOrders(ctx context.Context, req *OrdersNotificationsRequest, os chan<- *OrderResult) error {
+	if c.pollInterval <= 0 {
+		return fmt.Errorf("non-positive poll interval: %s", c.pollInterval)
+	}
+	var oreq *GetOrdersRequest
+	if req != nil && req.ProfileID != "" {
+		oreq = &GetOrdersRequest{ProfileID: req.ProfileID}
+	}
+
+	ctx, cancel := c.withClose(ctx)
+	orders, err := c.GetOrders(ctx, oreq)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to poll orders: %w", err)
+	}
+	seen := make(map[string]OrderState, len(orders))
+	for _, o := range orders {
+		seen[o.ID] = o.Meta.State
+	}
+
+	ticker := time.NewTicker(c.pollInterval)
+	go func() {
+		defer cancel()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				os <- &OrderResult{nil, c.doneErr(ctx)}
+
+				return
+			case <-ticker.C:
+				orders, err := c.GetOrders(ctx, oreq)
+				if err != nil {
+					if ctx.Err() == nil {
+						os <- &OrderResult{nil, fmt.Errorf("failed to poll orders: %w", err)}
+					}
+					continue
+				}
+
+				for _, o := range orders {
+					if s, ok := seen[o.ID]; ok && s == o.Meta.State {
+						continue
+					}
+					seen[o.ID] = o.Meta.State
+					os <- &OrderResult{o, nil}
+				}
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package monerium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+func TestSubscribeOrdersRejectsNonPositivePollInterval(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+	}), WithNotificationsTransport(NotificationsTransportPoll), WithPollInterval(0))
+
+	if err := c.SubscribeOrders(context.Background(), nil, make(chan *OrderResult)); err == nil {
+		t.Error("expected error")
+	}
+}
+
+// stalledWebsocketHandler accepts websocket connections and neither writes nor reads, so pings stay unanswered,
+// until stop is closed. It counts accepted connections in conns.
+func stalledWebsocketHandler(conns *int32, stop <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close(websocket.StatusNormalClosure, "")
+		atomic.AddInt32(conns, 1)
+		<-stop
+	}
+}
+
+func TestCloseStopsOpenStreams(t *testing.T) {
+	closedByClient := make(chan struct{})
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") == "" {
+			writeJSON(t, w, []*Order{})
+			return
+		}
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		for {
+			if _, _, err := conn.Read(context.Background()); err != nil {
+				close(closedByClient)
+				return
+			}
+		}
+	}), WithNotifyTick(5*time.Millisecond), WithPollInterval(5*time.Millisecond))
+
+	ctx := context.Background()
+	ws := make(chan *OrderResult, 100)
+	if err := c.OrdersNotifications(ctx, nil, ws); err != nil {
+		t.Fatalf("OrdersNotifications: %v", err)
+	}
+	poll := make(chan *OrderResult, 100)
+	if err := c.pollOrders(ctx, nil, poll); err != nil {
+		t.Fatalf("pollOrders: %v", err)
+	}
+
+	// Close concurrently with the streams, and more than once.
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Close(); err != nil {
+				t.Errorf("Close: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for name, results := range map[string]chan *OrderResult{"websocket": ws, "poll": poll} {
+		select {
+		case res := <-results:
+			if !errors.Is(res.Error, ErrClientClosed) {
+				t.Errorf("%s: final result = %+v, want ErrClientClosed", name, res)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: stream not stopped by Close", name)
+		}
+	}
+	select {
+	case <-closedByClient:
+	case <-time.After(5 * time.Second):
+		t.Fatal("websocket not closed by Close")
+	}
+
+	if err := c.OrdersNotifications(ctx, nil, ws); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("OrdersNotifications after Close = %v, want ErrClientClosed", err)
+	}
+}
+
+// waitForConnections waits until conns reaches n.
+func waitForConnections(t *testing.T, conns *int32, n int32) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(conns) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d connections, want at least %d", atomic.LoadInt32(conns), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestOrdersNotificationsReconnectsStalledConnection(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  ClientOption
+	}{
+		{"read timeout", WithWSReadTimeout(30 * time.Millisecond)},
+		{"unanswered ping", WithWSPingInterval(20 * time.Millisecond)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conns int32
+			stop := make(chan struct{})
+			c := newTestClient(t, stalledWebsocketHandler(&conns, stop), WithNotifyTick(5*time.Millisecond), tt.opt)
+			t.Cleanup(func() { close(stop) })
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			results := make(chan *OrderResult, 100)
+			if err := c.OrdersNotifications(ctx, nil, results); err != nil {
+				t.Fatalf("OrdersNotifications: %v", err)
+			}
+
+			waitForConnections(t, &conns, 2)
+		})
+	}
+}
+
+func TestOrdersNotificationsKeepsLiveConnection(t *testing.T) {
+	var conns int32
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		atomic.AddInt32(&conns, 1)
+		// Reading answers pings until the client closes the connection.
+		for {
+			if _, _, err := conn.Read(context.Background()); err != nil {
+				return
+			}
+		}
+	}), WithNotifyTick(5*time.Millisecond), WithWSPingInterval(50*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results := make(chan *OrderResult, 100)
+	if err := c.OrdersNotifications(ctx, nil, results); err != nil {
+		t.Fatalf("OrdersNotifications: %v", err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Errorf("got %d connections, want 1", n)
+	}
+	select {
+	case res := <-results:
+		t.Errorf("unexpected result %+v", res)
+	default:
+	}
+}
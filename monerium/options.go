@@ -0,0 +1,82 @@
+package monerium
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// RequestOption represents a configurable option of a single call, e.g. GetOrders.
+// Options are a shorthand for context helpers like WithRetryPolicy, and a way to get details
+// of the response not returned by the call, e.g. its headers.
+type RequestOption func(*requestConfig)
+
+// requestConfig contains settings of a single call.
+type requestConfig struct {
+	timeout time.Duration
+	retry   *RetryPolicy
+	labels  map[string]string
+	header  *http.Header
+}
+
+// WithCallTimeout bounds the time of the call, including retries, see RetryPolicy.
+func WithCallTimeout(d time.Duration) RequestOption {
+	return func(c *requestConfig) {
+		c.timeout = d
+	}
+}
+
+// WithCallRetryPolicy sets the retry policy of the call, like WithRetryPolicy does for a context.
+func WithCallRetryPolicy(p RetryPolicy) RequestOption {
+	return func(c *requestConfig) {
+		c.retry = &p
+	}
+}
+
+// WithCallLabels adds labels to the call, like WithLabels does for a context.
+func WithCallLabels(labels map[string]string) RequestOption {
+	return func(c *requestConfig) {
+		c.labels = labels
+	}
+}
+
+// WithResponseHeader stores headers of the call's last response in h, e.g. to read rate limits or
+// the correlation ID of a successful call. h is left unchanged if no response is received.
+func WithResponseHeader(h *http.Header) RequestOption {
+	return func(c *requestConfig) {
+		c.header = h
+	}
+}
+
+type requestConfigKey struct{}
+
+// applyRequestOptions returns a copy of ctx configured with opts. cancel must be called when the call returns.
+func applyRequestOptions(ctx context.Context, opts []RequestOption) (context.Context, context.CancelFunc) {
+	if len(opts) == 0 {
+		return ctx, func() {}
+	}
+
+	cfg := &requestConfig{}
+	for _, o := range opts {
+		o(cfg)
+	}
+	if cfg.retry != nil {
+		ctx = WithRetryPolicy(ctx, *cfg.retry)
+	}
+	if cfg.labels != nil {
+		ctx = WithLabels(ctx, cfg.labels)
+	}
+	ctx = context.WithValue(ctx, requestConfigKey{}, cfg)
+	if cfg.timeout > 0 {
+		return context.WithTimeout(ctx, cfg.timeout)
+	}
+
+	return ctx, func() {}
+}
+
+// captureResponseHeader stores h as requested by WithResponseHeader of a call made with ctx.
+func captureResponseHeader(ctx context.Context, h http.Header) {
+	if cfg, ok := ctx.Value(requestConfigKey{}).(*requestConfig); ok && cfg.header != nil {
+		*cfg.header = h.Clone()
+	}
+}
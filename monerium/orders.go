@@ -1,13 +1,19 @@
 package monerium
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/google/go-querystring/query"
 	"nhooyr.io/websocket"
 )
 
@@ -22,10 +28,25 @@ import (
 //
 // The authorization is implemented by requiring a signature derived from a private key (possession) in addition to a password (knowledge).
 // A message, the signature and the address associated with the private key used to sign must be added to the request payload.
+//
+// If the counterpart's country is empty, the one set by WithDefaultCounterpartCountry is sent; req isn't modified.
+// With WithMessageCheck, the message is checked before the request is sent.
+// With PlaceOrderRequest.ProfileID set, PlaceOrder fails with ErrNoWritePermission, without sending
+// the request, if the authenticated user has no write permission on the profile.
 func (c *Client) PlaceOrder(ctx context.Context, req *PlaceOrderRequest) (*Order, error) {
-	if err := req.Validate(); err != nil {
+	// The request, with the default country filled in, is validated by post.
+	req, err := c.withDefaultCountry(req)
+	if err != nil {
 		return nil, err
 	}
+	if err := c.checkMessage(req, time.Now()); err != nil {
+		return nil, err
+	}
+	if req != nil && req.ProfileID != "" {
+		if err := c.checkWritePermission(ctx, req.ProfileID); err != nil {
+			return nil, err
+		}
+	}
 
 	path := "/orders"
 	bs, err := c.post(ctx, path, req)
@@ -42,11 +63,22 @@ func (c *Client) PlaceOrder(ctx context.Context, req *PlaceOrderRequest) (*Order
 
 // PlaceOrderRequest contains parameters for placing an order.
 // Order can be placed either with set of Address, Currency and Chain or AccountID.
-// Memo is a reference of the SEPA transfer.
+// The order belongs to the profile owning the account, so to place an order on behalf of a profile
+// use one of its accounts (see Profile.Accounts); write permission on that profile is required.
+// ProfileID optionally names that profile: it isn't sent to Monerium, which has no profile-scoped
+// endpoint for placing orders, but PlaceOrder checks the permission against the auth context kept
+// by the latest GetAuthContext call, retrieving it if needed, to fail early.
+// Memo is a reference of the SEPA transfer, see BuildStructuredMemo.
 // SupportingDocumentID is a document to be attached for redeem order above certain limit.
 // Memo and SupportingDocumentID are optional.
 //
 // SupportingDocumentID is the ID of a uploaded file via UploadFile call.
+//
+// Message is expected to be built by BuildOrderMessage and signed with SignatureScheme.
+// Validate only checks it's present, Monerium is the authority on which messages it accepts;
+// see WithMessageCheck for checking it on the client side too.
+// SignatureScheme isn't sent to Monerium, it selects how Message is checked;
+// it defaults to SignatureSchemePersonalSign, the only scheme accepted by Monerium.
 type PlaceOrderRequest struct {
 	Address   string   `json:"address,omitempty"`
 	Currency  Currency `json:"currency,omitempty"`
@@ -61,6 +93,9 @@ type PlaceOrderRequest struct {
 
 	Memo                 string `json:"memo,omitempty"`
 	SupportingDocumentID string `json:"supportingDocumentId,omitempty"`
+
+	ProfileID       string          `json:"-"`
+	SignatureScheme SignatureScheme `json:"-"`
 }
 
 // Validate checks if PlaceOrderRequest is correct.
@@ -71,8 +106,23 @@ func (r *PlaceOrderRequest) Validate() error {
 	if r.Kind != OrderKindRedeem {
 		return errors.New("only redeem order is possible to be placed")
 	}
-	if r.Counterpart == nil {
-		return errors.New("order counterpart is missing")
+	if err := r.Counterpart.Validate(); err != nil {
+		return err
+	}
+	if r.Counterpart.Identifier.Standard == IdentifierStandardSCAN && r.Currency != "" && r.Currency != CurrencyGBP {
+		return fmt.Errorf("scan identifier requires %s currency, got %s", CurrencyGBP, r.Currency)
+	}
+	a, err := ParseAmount(r.Amount)
+	if err != nil {
+		return err
+	}
+	if a.Sign() <= 0 {
+		return fmt.Errorf("amount must be positive, got %s", r.Amount)
+	}
+	if r.Currency != "" {
+		if err := a.ValidateFor(r.Currency); err != nil {
+			return err
+		}
 	}
 	if r.Message == "" || r.Signature == "" {
 		return errors.New("message or signature missing")
@@ -88,13 +138,157 @@ func (r *PlaceOrderRequest) Validate() error {
 	return nil
 }
 
+// ErrNoWritePermission is returned by PlaceOrder when the authenticated user has no write permission
+// on the profile set in PlaceOrderRequest.ProfileID.
+var ErrNoWritePermission = errors.New("no write permission on the profile")
+
+// checkWritePermission checks that the authenticated user has write permission on the profile
+// identified by profileID, see PlaceOrderRequest.ProfileID.
+func (c *Client) checkWritePermission(ctx context.Context, profileID string) error {
+	ac, err := c.cachedAuthContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !ac.hasPermission(profileID, permWrite) {
+		return fmt.Errorf("profile %s: %w", profileID, ErrNoWritePermission)
+	}
+
+	return nil
+}
+
+// withDefaultCountry returns a copy of req with the default counterpart country set,
+// unless the country is already set or there's no default. A nil req or counterpart is returned as is.
+func (c *Client) withDefaultCountry(req *PlaceOrderRequest) (*PlaceOrderRequest, error) {
+	if c.defaultCountry == "" || req == nil || req.Counterpart == nil || req.Counterpart.Details.Country != "" {
+		return req, nil
+	}
+	if err := validateCountry(c.defaultCountry); err != nil {
+		return nil, fmt.Errorf("default counterpart country: %w", err)
+	}
+
+	r := *req
+	cp := *req.Counterpart
+	cp.Details.Country = c.defaultCountry
+	r.Counterpart = &cp
+
+	return &r, nil
+}
+
+// FindExistingOrder looks for an already placed order matching req, e.g. to avoid placing the same
+// redeem order twice after a retried submission. It returns the order and true when a match is found.
+//
+// An order matches when all of the following hold:
+//   - it is a redeem order which hasn't been rejected,
+//   - it was placed within the window set by WithDuplicateOrderWindow (10 minutes by default),
+//   - counterpart account, amount and memo are equal (amounts are compared as decimals, so "1" equals "1.00"),
+//   - when req uses AccountID, the account is the same; otherwise address (compared case-insensitively)
+//     and currency are equal, and so is chain when the order reports it.
+//
+// Only orders with the memo and the account or address of req are listed, rather than orders of every profile.
+// The check is based on server state, so an order placed concurrently might not be visible yet.
+func (c *Client) FindExistingOrder(ctx context.Context, req *PlaceOrderRequest) (*Order, bool, error) {
+	if req == nil {
+		return nil, false, errors.New("PlaceOrderRequest is required")
+	}
+	if req.Counterpart == nil {
+		return nil, false, errors.New("order counterpart is missing")
+	}
+
+	filter := &GetOrdersRequest{Memo: req.Memo, AccountID: req.AccountID}
+	if req.AccountID == "" {
+		filter.Address = req.Address
+	}
+	os, err := c.GetOrders(ctx, filter)
+	if err != nil {
+		return nil, false, err
+	}
+
+	since := time.Now().Add(-c.dupWindow)
+	for _, o := range os {
+		if o.Meta.PlacedAt.Before(since) {
+			continue
+		}
+		if isDuplicateOrder(o, req) {
+			return o, true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+// isDuplicateOrder reports whether o was placed with the same data as req.
+func isDuplicateOrder(o *Order, req *PlaceOrderRequest) bool {
+	if o.Kind != OrderKindRedeem || o.Meta.State == OrderStateRejected {
+		return false
+	}
+	if req.AccountID != "" {
+		if o.AccountID != req.AccountID {
+			return false
+		}
+	} else if o.Currency != req.Currency || !strings.EqualFold(o.Address, req.Address) ||
+		(o.Chain != "" && o.Chain != req.Chain) {
+		return false
+	}
+
+	return o.Memo == req.Memo &&
+		o.Counterpart.Identifier.sameAccount(&req.Counterpart.Identifier) &&
+		equalAmounts(o.Amount, req.Amount)
+}
+
+// CanPlaceOrder reports whether a redeem order in currency can be placed for the profile identified by profileID.
+// When it can't, a human-readable reason is returned. The profile must:
+//   - be accessible with write permission by the authenticated user,
+//   - have its KYC confirmed and approved,
+//   - have an approved account in currency.
+//
+// The balance isn't checked, as the amount of the order isn't known here; see GetBalancesForProfile.
+func (c *Client) CanPlaceOrder(ctx context.Context, profileID string, currency Currency) (bool, string, error) {
+	profileID, err := c.profileID(ctx, profileID)
+	if err != nil {
+		return false, "", err
+	}
+	if profileID == "" {
+		return false, "", errors.New("empty profileID")
+	}
+
+	ac, err := c.GetAuthContext(ctx)
+	if err != nil {
+		return false, "", err
+	}
+	if !ac.hasPermission(profileID, permWrite) {
+		return false, "no write permission on the profile", nil
+	}
+
+	p, err := c.GetProfile(ctx, &GetProfileRequest{ProfileID: profileID})
+	if err != nil {
+		return false, "", err
+	}
+	if p.KYC.State != KYCStateConfirmed || KYCOutcome(p.KYC.Outcome) != KYCOutcomeApproved {
+		return false, fmt.Sprintf("KYC is not approved (state: %s, outcome: %s)", p.KYC.State, p.KYC.Outcome), nil
+	}
+	hasAccount := false
+	for _, a := range p.Accounts {
+		if a.Currency == currency && a.State == AccountStateApproved && a.Address != "" {
+			hasAccount = true
+			break
+		}
+	}
+	if !hasAccount {
+		return false, fmt.Sprintf("no approved %s account", currency), nil
+	}
+
+	return true, "", nil
+}
+
 // Order represents a payment Order.
 // If order is rejected, the reason is stored in RejectedReason.
+// Counterpart is the beneficiary of a redeem order and the sender of an issue order.
 type Order struct {
 	ID                   string      `json:"id,omitempty"`
 	Profile              string      `json:"profile,omitempty"`
 	AccountID            string      `json:"accountId,omitempty"`
 	Address              string      `json:"address,omitempty"`
+	Chain                Chain       `json:"chain,omitempty"`
 	Kind                 OrderKind   `json:"kind,omitempty"`
 	Amount               string      `json:"amount,omitempty"`
 	Currency             Currency    `json:"currency,omitempty"`
@@ -105,20 +299,107 @@ type Order struct {
 	Meta                 OrderMeta   `json:"meta,omitempty"`
 }
 
+// SenderIBAN returns IBAN of the sender of an issue order, i.e. the one who paid.
+// It returns an empty string for other kinds of orders.
+func (o *Order) SenderIBAN() string {
+	if o.Kind != OrderKindIssue {
+		return ""
+	}
+
+	return o.Counterpart.Identifier.IBAN
+}
+
+// ToPlaceOrderRequest creates a request placing the order again, e.g. after it was rejected
+// for a fixable reason, so that the request can be corrected and resubmitted.
+// Account, amount, counterpart, memo and supporting document are carried over. Message and Signature
+// are left empty, as they must be built (see BuildOrderMessage) and signed anew. Orders don't say which
+// chain they were placed on, so Chain must be set if AccountID is empty.
+func (o *Order) ToPlaceOrderRequest() *PlaceOrderRequest {
+	cp := o.Counterpart
+
+	return &PlaceOrderRequest{
+		Address:              o.Address,
+		Currency:             o.Currency,
+		AccountID:            o.AccountID,
+		Kind:                 o.Kind,
+		Amount:               o.Amount,
+		Counterpart:          &cp,
+		Memo:                 o.Memo,
+		SupportingDocumentID: o.SupportingDocumentID,
+	}
+}
+
+// AwaitingReview reports whether the order with a supporting document waits for the document to be reviewed.
+// Monerium doesn't expose a separate review state: such order stays OrderStatePlaced
+// until it's approved, which is recorded in OrderMeta.ApprovedAt.
+func (o *Order) AwaitingReview() bool {
+	return o.SupportingDocumentID != "" &&
+		o.Meta.State == OrderStatePlaced &&
+		o.Meta.ApprovedAt.IsZero()
+}
+
+// IsPartiallyFilled reports whether less than the requested Amount was filled.
+// The filled amount is taken from OrderMeta.SentAmount or, if it's not set, from OrderMeta.ReceivedAmount.
+//
+// Monerium doesn't split orders, so a partial fill isn't expected; it indicates the amount was
+// adjusted during processing and needs reconciliation.
+func (o *Order) IsPartiallyFilled() bool {
+	r, err := o.remainingAmount()
+	if err != nil {
+		return false
+	}
+
+	return r.Sign() > 0
+}
+
+// RemainingAmount returns the difference between the requested Amount and the filled amount.
+// It fails if the order has no filled amount yet (see IsPartiallyFilled) or amounts are malformed.
+func (o *Order) RemainingAmount() (string, error) {
+	r, err := o.remainingAmount()
+	if err != nil {
+		return "", err
+	}
+
+	return formatAmount(r), nil
+}
+
+// remainingAmount returns the difference between the requested and filled amounts.
+func (o *Order) remainingAmount() (*big.Rat, error) {
+	filled := o.Meta.SentAmount
+	if filled == "" {
+		filled = o.Meta.ReceivedAmount
+	}
+	if filled == "" {
+		return nil, errors.New("order has no filled amount")
+	}
+
+	requested, err := parseAmount(o.Amount)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parseAmount(filled)
+	if err != nil {
+		return nil, err
+	}
+
+	return requested.Sub(requested, f), nil
+}
+
 // GetOrders retrieves all orders accessible by the authenticated user.
 // Query parameters passed in GetOrderRequest can be used to filter and sort the result.
 // GetOrderRequest can be nil, in that case no filters are applied.
-func (c *Client) GetOrders(ctx context.Context, req *GetOrdersRequest) ([]*Order, error) {
-	path := "/orders"
-	if req != nil {
-		v, err := query.Values(req)
-		if err != nil {
-			return nil, err
-		}
-		path = "/orders?" + v.Encode()
+// Memos are normalized as set by WithMemoTrim, both in the filter and in the result.
+func (c *Client) GetOrders(ctx context.Context, req *GetOrdersRequest, opts ...RequestOption) ([]*Order, error) {
+	ctx, cancel := applyRequestOptions(ctx, opts)
+	defer cancel()
+
+	if req != nil && req.Memo != "" && c.memoTrim != (memoTrim{}) {
+		r := *req
+		r.Memo = c.memoTrim.prefix + r.Memo + c.memoTrim.suffix
+		req = &r
 	}
 
-	bs, err := c.get(ctx, path)
+	bs, err := c.get(ctx, "/orders", req)
 	if err != nil {
 		return nil, err
 	}
@@ -126,6 +407,7 @@ func (c *Client) GetOrders(ctx context.Context, req *GetOrdersRequest) ([]*Order
 	if err = json.Unmarshal(bs, &os); err != nil {
 		return nil, err
 	}
+	c.memoTrim.apply(os...)
 
 	return os, nil
 }
@@ -138,29 +420,271 @@ type GetOrdersRequest struct {
 	State     OrderState `url:"state"`
 	AccountID string     `url:"accountId"`
 	ProfileID string     `url:"profile"`
+
+	SupportingDocumentID string `url:"supportingDocumentId,omitempty"`
 }
 
-// GetOrder retrieves order based on OrderID.
-func (c *Client) GetOrder(ctx context.Context, req *GetOrderRequest) (*Order, error) {
-	path := fmt.Sprintf("/orders/%s", req.OrderID)
+// Validate checks GetOrdersRequest. A nil request is valid, it applies no filters.
+func (r *GetOrdersRequest) Validate() error {
+	if r == nil {
+		return nil
+	}
+	if r.State != "" && !r.State.IsKnown() {
+		return fmt.Errorf("unknown order state: %q", r.State)
+	}
+
+	return nil
+}
 
-	bs, err := c.get(ctx, path)
+// GetProfileOrders retrieves orders of the profile identified by profileID, filtered by optional req.
+// It's the REST counterpart of OrdersNotificationsRequest.ProfileID. The REST API scopes orders
+// by the profile query parameter rather than by a /profiles/{id}/orders path, so req.ProfileID is overridden;
+// req isn't modified.
+func (c *Client) GetProfileOrders(ctx context.Context, profileID string, req *GetOrdersRequest) ([]*Order, error) {
+	profileID, err := c.profileID(ctx, profileID)
 	if err != nil {
 		return nil, err
 	}
+	if profileID == "" {
+		return nil, errors.New("empty profileID")
+	}
+
+	var r GetOrdersRequest
+	if req != nil {
+		r = *req
+	}
+	r.ProfileID = profileID
+
+	return c.GetOrders(ctx, &r)
+}
+
+// GetAllOrders retrieves orders of every profile accessible by the authenticated user.
+// Profiles are listed via GetProfiles and their orders are fetched concurrently (see WithConcurrency).
+// Optional req is used to filter orders of each profile; its ProfileID is ignored.
+// Orders are de-duplicated by ID and their Profile is set if missing.
+func (c *Client) GetAllOrders(ctx context.Context, req *GetOrdersRequest) ([]*Order, error) {
+	ps, err := c.GetProfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pos := make([][]*Order, len(ps))
+	errs := c.runConcurrently(ctx, len(ps), func(i int) error {
+		var r GetOrdersRequest
+		if req != nil {
+			r = *req
+		}
+		r.ProfileID = ps[i].ID
+
+		os, err := c.GetOrders(ctx, &r)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to get orders of profile %s: %w", ps[i].ID, err)
+		}
+		for _, o := range os {
+			if o.Profile == "" {
+				o.Profile = ps[i].ID
+			}
+		}
+		pos[i] = os
+
+		return nil
+	})
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var all []*Order
+	seen := make(map[string]bool)
+	for _, os := range pos {
+		for _, o := range os {
+			if seen[o.ID] {
+				continue
+			}
+			seen[o.ID] = true
+			all = append(all, o)
+		}
+	}
+
+	return all, nil
+}
+
+// GetOrdersByDocument retrieves orders referencing the supporting document identified by documentID,
+// i.e. the ID of a file uploaded via UploadFile.
+func (c *Client) GetOrdersByDocument(ctx context.Context, documentID string) ([]*Order, error) {
+	if documentID == "" {
+		return nil, errors.New("empty documentID")
+	}
+
+	return c.GetOrders(ctx, &GetOrdersRequest{SupportingDocumentID: documentID})
+}
+
+// GetOrder retrieves order based on OrderID.
+// If the Client is created WithOrderCache, orders in a terminal state are served from the cache.
+func (c *Client) GetOrder(ctx context.Context, req *GetOrderRequest) (*Order, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	if c.isClosed() {
+		return nil, ErrClientClosed
+	}
+	bs, ok := c.orders.get(req.OrderID)
+	if !ok {
+		path := fmt.Sprintf("/orders/%s", req.OrderID)
+
+		var err error
+		if bs, err = c.get(ctx, path, nil); err != nil {
+			return nil, err
+		}
+	}
 	var o *Order
-	if err = json.Unmarshal(bs, &o); err != nil {
+	if err := json.Unmarshal(bs, &o); err != nil {
 		return nil, err
 	}
+	if !ok && o != nil && o.Meta.State.IsTerminal() {
+		c.orders.set(req.OrderID, bs)
+	}
+	c.memoTrim.apply(o)
 
 	return o, nil
 }
 
+// orderCache caches response bodies of terminal orders for ttl. Zero ttl disables caching.
+// Bodies are kept rather than orders, so that every caller gets its own copy.
+type orderCache struct {
+	ttl time.Duration
+
+	mu     sync.Mutex
+	orders map[string]cachedOrder
+}
+
+// cachedOrder is a response body of an order and when it was fetched.
+type cachedOrder struct {
+	body      []byte
+	fetchedAt time.Time
+}
+
+// get returns the cached body of order identified by id, unless it's missing or expired.
+func (oc *orderCache) get(id string) ([]byte, bool) {
+	if oc.ttl <= 0 {
+		return nil, false
+	}
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	co, ok := oc.orders[id]
+	if !ok || time.Since(co.fetchedAt) >= oc.ttl {
+		return nil, false
+	}
+
+	return co.body, true
+}
+
+// set caches body of order identified by id, dropping expired orders.
+func (oc *orderCache) set(id string, body []byte) {
+	if oc.ttl <= 0 {
+		return
+	}
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	if oc.orders == nil {
+		oc.orders = make(map[string]cachedOrder)
+	}
+	now := time.Now()
+	for k, co := range oc.orders {
+		if now.Sub(co.fetchedAt) >= oc.ttl {
+			delete(oc.orders, k)
+		}
+	}
+	oc.orders[id] = cachedOrder{body: body, fetchedAt: now}
+}
+
 // GetOrderRequest contains optional query parameters that can be used to filter results.
 type GetOrderRequest struct {
 	OrderID string `url:"orderId"`
 }
 
+// Validate checks GetOrderRequest.
+func (r *GetOrderRequest) Validate() error {
+	if r == nil {
+		return errors.New("GetOrderRequest is required")
+	}
+	if r.OrderID == "" {
+		return errors.New("empty orderID")
+	}
+
+	return nil
+}
+
+// GetOrderHistory retrieves state changes of an order identified by orderID, sorted from the oldest.
+//
+// Monerium API doesn't expose a dedicated history endpoint, so the history is reconstructed
+// from the timestamps kept in OrderMeta. Approval of an order is reported as OrderStatePending.
+func (c *Client) GetOrderHistory(ctx context.Context, orderID string) ([]*OrderEvent, error) {
+	if orderID == "" {
+		return nil, errors.New("empty orderID")
+	}
+
+	o, err := c.GetOrder(ctx, &GetOrderRequest{OrderID: orderID})
+	if err != nil {
+		return nil, err
+	}
+
+	return newOrderEventsFrom(&o.Meta), nil
+}
+
+// OrderEvent represents a single state change of an Order.
+type OrderEvent struct {
+	State OrderState
+	Time  time.Time
+}
+
+// ErrPlacerNotAccessible is returned by ResolvePlacedBy when details of the user who placed the order
+// can't be read with the current credentials.
+var ErrPlacerNotAccessible = errors.New("order placer is not accessible")
+
+// ResolvePlacedBy resolves OrderMeta.PlacedBy user ID into user details.
+//
+// Monerium API exposes details only of the authenticated user (see GetAuthContext),
+// so orders placed by other users result in ErrPlacerNotAccessible.
+func (c *Client) ResolvePlacedBy(ctx context.Context, o *Order) (*OrderPlacer, error) {
+	if o == nil {
+		return nil, errors.New("order is required")
+	}
+	if o.Meta.PlacedBy == "" {
+		return nil, errors.New("order has no placer")
+	}
+
+	ac, err := c.GetAuthContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ac.UserID != o.Meta.PlacedBy {
+		return nil, ErrPlacerNotAccessible
+	}
+
+	return &OrderPlacer{
+		UserID: ac.UserID,
+		Name:   ac.Name,
+		Email:  ac.Email,
+	}, nil
+}
+
+// OrderPlacer represents the user who placed an Order.
+type OrderPlacer struct {
+	UserID string
+	Name   string
+	Email  string
+}
+
 // OrdersNotifications streams order updates over a channel.
 //
 // The websocket will emit the same order object up to three times, once for the following state changes:
@@ -171,9 +695,20 @@ type GetOrderRequest struct {
 // Pending state is optional and Order might transform from placed straight to processed.
 // OrderResult contains Order on sucessfull response or Error on failure.
 func (c *Client) OrdersNotifications(ctx context.Context, req *OrdersNotificationsRequest, os chan<- *OrderResult) error {
-	tok, err := c.tokenSource.Token()
-	if err != nil {
-		return fmt.Errorf("failed to get auth token: %w", err)
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	return c.ordersNotifications(ctx, req, os)
+}
+
+// ordersNotifications implements OrdersNotifications for validated req.
+func (c *Client) ordersNotifications(ctx context.Context, req *OrdersNotificationsRequest, os chan<- *OrderResult) error {
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+	if err := c.validateWebsocketURL(); err != nil {
+		return err
 	}
 
 	path := c.wsURL + "/orders"
@@ -181,27 +716,56 @@ func (c *Client) OrdersNotifications(ctx context.Context, req *OrdersNotificatio
 		path = fmt.Sprintf("%s/profiles/%s/orders", c.wsURL, req.ProfileID)
 	}
 
-	wc, err := dialWebsocket(ctx, path, tok)
+	ctx, cancel := c.withClose(ctx)
+	wc, err := c.connectWebsocket(ctx, path)
 	if err != nil {
-		return fmt.Errorf("failed to dial websocket: %w", err)
+		cancel()
+		return err
 	}
+	// connCtx lives as long as the current connection, it's canceled when the connection is found dead.
+	connCtx, connCancel := c.watchLiveness(ctx, wc)
 
 	ticker := time.NewTicker(c.notifyTick)
 	go func() {
+		defer cancel()
 		for {
 			select {
 			case <-ctx.Done():
-				wc.Close(websocket.StatusNormalClosure, "stopping connection")
-				os <- &OrderResult{nil, ctx.Err()}
+				if wc != nil {
+					wc.Close(websocket.StatusNormalClosure, "stopping connection")
+				}
+				connCancel()
+				os <- &OrderResult{nil, c.doneErr(ctx)}
 
 				return
 			case <-ticker.C:
-				o, err := readOrder(ctx, wc)
-				if err != nil {
-					os <- &OrderResult{nil, fmt.Errorf("failed to read order: %w", err)}
+				if wc == nil {
+					if wc, err = c.connectWebsocket(ctx, path); err != nil {
+						os <- &OrderResult{nil, fmt.Errorf("failed to reconnect: %w", err)}
+						continue
+					}
+					connCtx, connCancel = c.watchLiveness(ctx, wc)
 				}
 
-				os <- &OrderResult{o, nil}
+				orders, err := readOrders(connCtx, wc, c.wsReadTimeout)
+				c.memoTrim.apply(orders...)
+				for _, o := range orders {
+					os <- &OrderResult{o, nil}
+				}
+				if errors.Is(err, errReadTimeout) || err != nil && ctx.Err() == nil && connCtx.Err() != nil {
+					// The connection is closed when a read is interrupted, it's re-established on the next tick.
+					connCancel()
+					wc = nil
+					continue
+				}
+				if err != nil && ctx.Err() == nil {
+					os <- &OrderResult{nil, fmt.Errorf("failed to read order: %w", err)}
+				}
+				if errors.Is(err, errReadWebsocket) {
+					// The connection is closed by the failed read, it's re-established on the next tick.
+					connCancel()
+					wc = nil
+				}
 			}
 		}
 	}()
@@ -214,6 +778,18 @@ type OrdersNotificationsRequest struct {
 	ProfileID string
 }
 
+// Validate checks OrdersNotificationsRequest. A nil request is valid, it subscribes to orders of all profiles.
+func (r *OrdersNotificationsRequest) Validate() error {
+	if r == nil {
+		return nil
+	}
+	if url.PathEscape(r.ProfileID) != r.ProfileID {
+		return fmt.Errorf("invalid profileID: %q", r.ProfileID)
+	}
+
+	return nil
+}
+
 // OrderResult contains Order response on success or Error with failure reason.
 type OrderResult struct {
 	Order *Order
@@ -223,13 +799,41 @@ type OrderResult struct {
 // OrderKind represents Order kind.
 // Only redeem order can be placed via API.
 // Issue orders are created via money transfer over SEPA to IBAN number provided by Monerium.
+//
+// Kinds unknown to the SDK are kept verbatim when unmarshalling, so no data is lost;
+// use IsKnown to detect them and compare against the raw value if needed,
+// or ParseOrderKind to map them to OrderKindUnknown.
 type OrderKind string
 
 const (
 	OrderKindRedeem OrderKind = "redeem"
 	OrderKindIssue  OrderKind = "issue"
+	// OrderKindUnknown is returned by ParseOrderKind for kinds unknown to the SDK.
+	// It's never sent by Monerium.
+	OrderKindUnknown OrderKind = "unknown"
 )
 
+// ParseOrderKind parses s as one of kinds known to the SDK, ignoring case.
+// For other kinds it returns OrderKindUnknown and false.
+func ParseOrderKind(s string) (OrderKind, bool) {
+	k := OrderKind(strings.ToLower(s))
+	if !k.IsKnown() {
+		return OrderKindUnknown, false
+	}
+
+	return k, true
+}
+
+// IsKnown reports whether k is one of kinds known to the SDK.
+func (k OrderKind) IsKnown() bool {
+	switch k {
+	case OrderKindRedeem, OrderKindIssue:
+		return true
+	default:
+		return false
+	}
+}
+
 // OrderKind represents Order kind.
 type OrderState string
 
@@ -240,7 +844,24 @@ const (
 	OrderStateRejected  OrderState = "rejected"
 )
 
+// IsKnown reports whether s is one of states known to the SDK.
+func (s OrderState) IsKnown() bool {
+	switch s {
+	case OrderStatePlaced, OrderStatePending, OrderStateProcessed, OrderStateRejected:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsTerminal reports whether s is a final state, after which the order doesn't change anymore.
+func (s OrderState) IsTerminal() bool {
+	return s == OrderStateProcessed || s == OrderStateRejected
+}
+
 // OrderMeta represents the metadata of an Order.
+// Timestamps are sent by Monerium as RFC3339 with milliseconds, e.g. "2023-05-29T12:45:29.307Z".
+// A zero timestamp means the order hasn't reached the corresponding state.
 type OrderMeta struct {
 	ApprovedAt     time.Time  `json:"approvedAt,omitempty"`
 	ProcessedAt    time.Time  `json:"processedAt,omitempty"`
@@ -258,12 +879,127 @@ type Counterpart struct {
 	Details    CounterpartDetails `json:"details,omitempty"`
 }
 
+// Validate checks if Counterpart is correct.
+func (c *Counterpart) Validate() error {
+	if c == nil {
+		return errors.New("order counterpart is missing")
+	}
+
+	return c.Identifier.Validate()
+}
+
+// NewCounterpart creates a Counterpart of a bank account identified by IBAN.
+// iban may contain spaces and lower case letters, it's normalized and its checksum is verified.
+// country is an ISO 3166-1 alpha-2 code of the counterpart's country, e.g. "DE".
+func NewCounterpart(iban, firstName, lastName, country string) (*Counterpart, error) {
+	iban = normalizeIBAN(iban)
+	if err := validateIBAN(iban); err != nil {
+		return nil, err
+	}
+	if err := validateCountry(country); err != nil {
+		return nil, err
+	}
+
+	return &Counterpart{
+		Identifier: Identifier{
+			Standard: IdentifierStandardIBAN,
+			IBAN:     iban,
+		},
+		Details: CounterpartDetails{
+			Country:   country,
+			FirstName: firstName,
+			LastName:  lastName,
+		},
+	}, nil
+}
+
+// NewSCANCounterpart creates a Counterpart of a UK bank account identified by sort code and account number.
+// Such counterpart is used for GBP redeem orders to UK domestic accounts.
+func NewSCANCounterpart(sortCode, accountNumber, firstName, lastName string) (*Counterpart, error) {
+	c := &Counterpart{
+		Identifier: Identifier{
+			Standard:      IdentifierStandardSCAN,
+			SortCode:      sortCode,
+			AccountNumber: accountNumber,
+		},
+		Details: CounterpartDetails{
+			Country:   "GB",
+			FirstName: firstName,
+			LastName:  lastName,
+		},
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
 // Identifier represents the identifier of a Counterpart.
+// IBAN is used with IdentifierStandardIBAN, SortCode and AccountNumber with IdentifierStandardSCAN.
 type Identifier struct {
-	Standard string `json:"standard,omitempty"`
-	IBAN     string `json:"iban,omitempty"`
+	Standard      IdentifierStandard `json:"standard,omitempty"`
+	IBAN          string             `json:"iban,omitempty"`
+	SortCode      string             `json:"sortCode,omitempty"`
+	AccountNumber string             `json:"accountNumber,omitempty"`
+}
+
+// Validate checks if Identifier data matches its Standard.
+func (i *Identifier) Validate() error {
+	switch i.Standard {
+	case IdentifierStandardIBAN:
+		if i.IBAN == "" {
+			return errors.New("IBAN is required for iban identifier")
+		}
+		if i.SortCode != "" || i.AccountNumber != "" {
+			return errors.New("iban identifier can't have sort code or account number set")
+		}
+	case IdentifierStandardSCAN:
+		if i.IBAN != "" {
+			return errors.New("scan identifier can't have IBAN set")
+		}
+		if !isDigits(i.SortCode, 6) {
+			return errors.New("sort code of 6 digits is required for scan identifier")
+		}
+		if !isDigits(i.AccountNumber, 8) {
+			return errors.New("account number of 8 digits is required for scan identifier")
+		}
+	default:
+		return fmt.Errorf("unsupported identifier standard: %q", i.Standard)
+	}
+
+	return nil
 }
 
+// sameAccount reports whether i and other identify the same bank account.
+// An empty Standard is treated as IdentifierStandardIBAN.
+func (i *Identifier) sameAccount(other *Identifier) bool {
+	standard := func(id *Identifier) IdentifierStandard {
+		if id.Standard == "" {
+			return IdentifierStandardIBAN
+		}
+		return id.Standard
+	}
+	if standard(i) != standard(other) {
+		return false
+	}
+	if standard(i) == IdentifierStandardSCAN {
+		return i.SortCode == other.SortCode && i.AccountNumber == other.AccountNumber
+	}
+
+	return normalizeIBAN(i.IBAN) == normalizeIBAN(other.IBAN)
+}
+
+// IdentifierStandard represents a standard of a bank account identifier.
+type IdentifierStandard string
+
+const (
+	// IdentifierStandardIBAN identifies an account by IBAN.
+	IdentifierStandardIBAN IdentifierStandard = "iban"
+	// IdentifierStandardSCAN identifies a UK account by sort code and account number.
+	IdentifierStandardSCAN IdentifierStandard = "scan"
+)
+
 // CounterpartDetails represents the details of a Counterpart.
 type CounterpartDetails struct {
 	Country   string `json:"country,omitempty"`
@@ -280,6 +1016,16 @@ const (
 	ChainGnosis   Chain = "gnosis"
 )
 
+// IsKnown reports whether c is one of chains known to the SDK.
+func (c Chain) IsKnown() bool {
+	switch c {
+	case ChainEthereum, ChainPolygon, ChainGnosis:
+		return true
+	default:
+		return false
+	}
+}
+
 // Network represents supported blockchain networks.
 type Network string
 
@@ -290,29 +1036,202 @@ const (
 	NetworkChiado  Network = "chiado"
 )
 
-// readOrder reads Order from websocket connection.
-func readOrder(ctx context.Context, conn *websocket.Conn) (*Order, error) {
-	mt, bs, err := conn.Read(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read from websocket: %w", err)
+// IsKnown reports whether n is one of networks known to the SDK.
+func (n Network) IsKnown() bool {
+	switch n {
+	case NetworkMainnet, NetworkGoerli, NetworkMumbai, NetworkChiado:
+		return true
+	default:
+		return false
+	}
+}
+
+// chainTestnets maps chains to their known test networks.
+var chainTestnets = map[Chain]Network{
+	ChainEthereum: NetworkGoerli,
+	ChainPolygon:  NetworkMumbai,
+	ChainGnosis:   NetworkChiado,
+}
+
+// HasNetwork reports whether n is a known network of chain c, e.g. mumbai is a network of polygon
+// but not of ethereum. It returns false if c or n is unknown to the SDK.
+func (c Chain) HasNetwork(n Network) bool {
+	testnet, ok := chainTestnets[c]
+
+	return ok && (n == NetworkMainnet || n == testnet)
+}
+
+// ChainNetworkMismatchError reports a pair of chain and network known to the SDK which don't belong together.
+type ChainNetworkMismatchError struct {
+	Chain   Chain
+	Network Network
+}
+
+// Error implements error interface.
+func (e *ChainNetworkMismatchError) Error() string {
+	return fmt.Sprintf("network %s doesn't belong to chain %s", e.Network, e.Chain)
+}
+
+// readOrders reads Orders from a single websocket text message, skipping any other messages.
+// Orders decoded before a failure are returned along with the error.
+// If timeout is positive and no message arrives in time, errReadTimeout is returned
+// and the connection is closed.
+func readOrders(ctx context.Context, conn *websocket.Conn, timeout time.Duration) ([]*Order, error) {
+	rctx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		rctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
-	if mt != websocket.MessageText {
-		return nil, fmt.Errorf("unsupported message type: %s", mt)
+
+	var bs []byte
+	for {
+		mt, msg, err := conn.Read(rctx)
+		if err != nil {
+			if ctx.Err() == nil && rctx.Err() != nil {
+				return nil, errReadTimeout
+			}
+			return nil, fmt.Errorf("%w: %w", errReadWebsocket, err)
+		}
+		// Control frames are handled by the connection, other non-text messages don't carry orders.
+		if mt == websocket.MessageText {
+			bs = msg
+			break
+		}
 	}
-	o, err := newOrderFrom(bs)
+
+	os, err := newOrdersFrom(bs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build order: %w", err)
+		return os, fmt.Errorf("failed to build order: %w", err)
 	}
 
-	return o, nil
+	return os, nil
 }
 
-// newOrderFrom returns a new Order from slice of bytes.
-func newOrderFrom(bs []byte) (*Order, error) {
-	var o Order
-	if err := json.Unmarshal(bs, &o); err != nil {
-		return nil, err
+// newOrdersFrom returns Orders from slice of bytes.
+// The slice might contain a stream of concatenated JSON objects, each representing an Order.
+func newOrdersFrom(bs []byte) ([]*Order, error) {
+	var os []*Order
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	for {
+		var o Order
+		err := dec.Decode(&o)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return os, err
+		}
+		os = append(os, &o)
+	}
+	if len(os) == 0 {
+		return nil, errors.New("empty message")
 	}
 
-	return &o, nil
+	return os, nil
+}
+
+// newOrderEventsFrom returns state changes recorded in OrderMeta, sorted chronologically.
+func newOrderEventsFrom(m *OrderMeta) []*OrderEvent {
+	ts := []struct {
+		state OrderState
+		at    time.Time
+	}{
+		{OrderStatePlaced, m.PlacedAt},
+		{OrderStatePending, m.ApprovedAt},
+		{OrderStateProcessed, m.ProcessedAt},
+		{OrderStateRejected, m.RejectedAt},
+	}
+
+	var es []*OrderEvent
+	for _, t := range ts {
+		if t.at.IsZero() {
+			continue
+		}
+		es = append(es, &OrderEvent{State: t.state, Time: t.at})
+	}
+	sort.SliceStable(es, func(i, j int) bool {
+		return es[i].Time.Before(es[j].Time)
+	})
+
+	return es
+}
+
+// normalizeIBAN removes spaces from the IBAN and converts it to upper case.
+func normalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.ReplaceAll(iban, " ", ""))
+}
+
+// validateIBAN checks format and checksum of normalized iban.
+func validateIBAN(iban string) error {
+	if len(iban) < 15 || len(iban) > 34 {
+		return fmt.Errorf("invalid IBAN length: %q", iban)
+	}
+	if !isLetters(iban[:2]) || !isDigits(iban[2:4], 2) {
+		return fmt.Errorf("invalid IBAN country code or check digits: %q", iban)
+	}
+
+	// Move country code and check digits to the end.
+	rem, ok := mod97(iban[4:] + iban[:4])
+	if !ok {
+		return fmt.Errorf("invalid IBAN characters: %q", iban)
+	}
+	if rem != 1 {
+		return fmt.Errorf("invalid IBAN checksum: %q", iban)
+	}
+
+	return nil
+}
+
+// mod97 replaces letters of s by numbers (A=10, ..., Z=35) and computes the remainder of division by 97
+// digit by digit, as done by ISO 7064 checksums of IBANs and creditor references.
+// It reports false if s contains anything but digits and upper case letters.
+func mod97(s string) (int, bool) {
+	rem := 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			rem = (rem*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			rem = (rem*100 + int(r-'A') + 10) % 97
+		default:
+			return 0, false
+		}
+	}
+
+	return rem, true
+}
+
+// validateCountry checks if country is formatted as ISO 3166-1 alpha-2 code.
+func validateCountry(country string) error {
+	if len(country) != 2 || !isLetters(country) {
+		return fmt.Errorf("invalid country code: %q", country)
+	}
+
+	return nil
+}
+
+// isLetters reports whether s consists of upper case ASCII letters only.
+func isLetters(s string) bool {
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isDigits reports whether s consists of exactly n decimal digits.
+func isDigits(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
 }
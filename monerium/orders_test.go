@@ -0,0 +1,723 @@
+package monerium
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+func TestIsDuplicateOrderComparesCounterpartAccounts(t *testing.T) {
+	scan := func(sortCode, accountNumber string) Counterpart {
+		return Counterpart{Identifier: Identifier{Standard: IdentifierStandardSCAN, SortCode: sortCode, AccountNumber: accountNumber}}
+	}
+	iban := func(iban string) Counterpart {
+		return Counterpart{Identifier: Identifier{Standard: IdentifierStandardIBAN, IBAN: iban}}
+	}
+
+	tests := []struct {
+		name     string
+		placed   Counterpart
+		req      Counterpart
+		expected bool
+	}{
+		{"same scan account", scan("123456", "12345678"), scan("123456", "12345678"), true},
+		{"other scan account number", scan("123456", "12345678"), scan("123456", "87654321"), false},
+		{"other sort code", scan("123456", "12345678"), scan("654321", "12345678"), false},
+		{"same iban", iban("GB33BUKB20201555555555"), iban("gb33 bukb 2020 1555 5555 55"), true},
+		{"other iban", iban("GB33BUKB20201555555555"), iban("GB94BARC10201530093459"), false},
+		{"iban and scan", iban("GB33BUKB20201555555555"), scan("202015", "55555555"), false},
+		{"iban without standard", Counterpart{Identifier: Identifier{IBAN: "GB33BUKB20201555555555"}}, iban("GB33BUKB20201555555555"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Order{Kind: OrderKindRedeem, Currency: CurrencyGBP, Amount: "10", Memo: "rent", Counterpart: tt.placed}
+			cp := tt.req
+			req := &PlaceOrderRequest{Currency: CurrencyGBP, Amount: "10.00", Memo: "rent", Counterpart: &cp}
+
+			if got := isDuplicateOrder(o, req); got != tt.expected {
+				t.Errorf("isDuplicateOrder = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewOrdersFromDecodesEveryObjectOfFrame(t *testing.T) {
+	os, err := newOrdersFrom([]byte(`{"id":"o1"}` + "\n" + `{"id":"o2"}`))
+	if err != nil {
+		t.Fatalf("newOrdersFrom: %v", err)
+	}
+	if len(os) != 2 || os[0].ID != "o1" || os[1].ID != "o2" {
+		t.Errorf("orders = %+v, want o1 and o2", os)
+	}
+
+	os, err = newOrdersFrom([]byte(`{"id":"o1"}{"id":`))
+	if err == nil {
+		t.Error("expected error on trailing partial object")
+	}
+	if len(os) != 1 || os[0].ID != "o1" {
+		t.Errorf("orders = %+v, want o1 decoded before the error", os)
+	}
+
+	if _, err = newOrdersFrom([]byte(" ")); err == nil {
+		t.Error("expected error on empty frame")
+	}
+}
+
+func TestOrdersNotificationsEmitsEveryOrderOfFrame(t *testing.T) {
+	stop := make(chan struct{})
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close(websocket.StatusNormalClosure, "")
+		if err = conn.Write(r.Context(), websocket.MessageText, []byte(`{"id":"o1"}{"id":"o2"}`)); err != nil {
+			t.Errorf("write frame: %v", err)
+		}
+		<-stop
+	}), WithNotifyTick(time.Millisecond))
+	t.Cleanup(func() { close(stop) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results := make(chan *OrderResult, 10)
+	if err := c.OrdersNotifications(ctx, nil, results); err != nil {
+		t.Fatalf("OrdersNotifications: %v", err)
+	}
+
+	for _, id := range []string{"o1", "o2"} {
+		select {
+		case res := <-results:
+			if res.Error != nil || res.Order.ID != id {
+				t.Fatalf("result = %+v, want order %s", res, id)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("order %s not emitted", id)
+		}
+	}
+}
+
+func TestIsDuplicateOrderComparesAddressAndChain(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  string
+		chain    Chain
+		expected bool
+	}{
+		{"same address and chain", "0xAbC", ChainGnosis, true},
+		{"address in other case", "0xabc", ChainGnosis, true},
+		{"chain not reported", "0xAbC", "", true},
+		{"other address", "0xdef", ChainGnosis, false},
+		{"other chain", "0xAbC", ChainPolygon, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Order{Kind: OrderKindRedeem, Address: tt.address, Chain: tt.chain, Currency: CurrencyEUR, Amount: "10", Memo: "rent",
+				Counterpart: Counterpart{Identifier: Identifier{IBAN: "GR1601101250000000012300695"}}}
+			req := &PlaceOrderRequest{Address: "0xABC", Chain: ChainGnosis, Currency: CurrencyEUR, Amount: "10", Memo: "rent",
+				Counterpart: &Counterpart{Identifier: Identifier{IBAN: "GR1601101250000000012300695"}}}
+
+			if got := isDuplicateOrder(o, req); got != tt.expected {
+				t.Errorf("isDuplicateOrder = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFindExistingOrderFiltersListedOrders(t *testing.T) {
+	var query url.Values
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		writeJSON(t, w, []*Order{})
+	}))
+	cp := &Counterpart{Identifier: Identifier{IBAN: "GR1601101250000000012300695"}}
+
+	if _, _, err := c.FindExistingOrder(context.Background(), &PlaceOrderRequest{
+		Address: "0x1", Chain: ChainGnosis, Currency: CurrencyEUR, Amount: "10", Memo: "rent", Counterpart: cp,
+	}); err != nil {
+		t.Fatalf("FindExistingOrder: %v", err)
+	}
+	if query.Get("memo") != "rent" || query.Get("address") != "0x1" || query.Get("accountId") != "" {
+		t.Errorf("query = %v, want memo and address filters", query)
+	}
+
+	if _, _, err := c.FindExistingOrder(context.Background(), &PlaceOrderRequest{
+		AccountID: "a1", Amount: "10", Memo: "rent", Counterpart: cp,
+	}); err != nil {
+		t.Fatalf("FindExistingOrder: %v", err)
+	}
+	if query.Get("memo") != "rent" || query.Get("accountId") != "a1" || query.Get("address") != "" {
+		t.Errorf("query = %v, want memo and account filters", query)
+	}
+}
+
+func TestGetOrderHistory(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orders/o1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "o1",
+			"kind": "redeem",
+			"meta": {
+				"state": "processed",
+				"placedAt": "2023-05-29T12:45:29.307Z",
+				"approvedAt": "2023-05-29T12:46:00.000Z",
+				"processedAt": "2023-05-29T13:01:12.500Z"
+			}
+		}`))
+	}))
+
+	es, err := c.GetOrderHistory(context.Background(), "o1")
+	if err != nil {
+		t.Fatalf("GetOrderHistory: %v", err)
+	}
+
+	expected := []struct {
+		state OrderState
+		at    string
+	}{
+		{OrderStatePlaced, "2023-05-29T12:45:29.307Z"},
+		{OrderStatePending, "2023-05-29T12:46:00Z"},
+		{OrderStateProcessed, "2023-05-29T13:01:12.5Z"},
+	}
+	if len(es) != len(expected) {
+		t.Fatalf("got %d events, want %d", len(es), len(expected))
+	}
+	for i, e := range expected {
+		if es[i].State != e.state || es[i].Time.Format(time.RFC3339Nano) != e.at {
+			t.Errorf("event %d = %s at %s, want %s at %s", i, es[i].State, es[i].Time.Format(time.RFC3339Nano), e.state, e.at)
+		}
+	}
+
+	if _, err := c.GetOrderHistory(context.Background(), ""); err == nil {
+		t.Error("expected error for empty orderID")
+	}
+}
+
+func TestGetOrdersByDocumentEncodesQuery(t *testing.T) {
+	var query url.Values
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		writeJSON(t, w, []*Order{{ID: "o1", SupportingDocumentID: "doc 1&2"}})
+	}))
+
+	os, err := c.GetOrdersByDocument(context.Background(), "doc 1&2")
+	if err != nil {
+		t.Fatalf("GetOrdersByDocument: %v", err)
+	}
+	if got := query.Get("supportingDocumentId"); got != "doc 1&2" {
+		t.Errorf("supportingDocumentId = %q, want %q", got, "doc 1&2")
+	}
+	if len(os) != 1 || os[0].ID != "o1" {
+		t.Errorf("orders = %+v, want o1", os)
+	}
+
+	if _, err = c.GetOrdersByDocument(context.Background(), ""); err == nil {
+		t.Error("expected error for empty documentID")
+	}
+
+	if _, err = c.GetOrders(context.Background(), &GetOrdersRequest{ProfileID: "p1"}); err != nil {
+		t.Fatalf("GetOrders: %v", err)
+	}
+	if _, ok := query["supportingDocumentId"]; ok {
+		t.Error("empty supportingDocumentId is sent")
+	}
+}
+
+func TestOrderKindUnknownRoundTrips(t *testing.T) {
+	var o Order
+	if err := json.Unmarshal([]byte(`{"id":"o1","kind":"internal_transfer"}`), &o); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if o.Kind.IsKnown() {
+		t.Errorf("kind %q reported as known", o.Kind)
+	}
+	if o.Kind != "internal_transfer" {
+		t.Errorf("kind = %q, want the raw value", o.Kind)
+	}
+
+	bs, err := json.Marshal(&o)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if !bytes.Contains(bs, []byte(`"kind":"internal_transfer"`)) {
+		t.Errorf("marshalled order %s lost the kind", bs)
+	}
+
+	for _, k := range []OrderKind{OrderKindRedeem, OrderKindIssue} {
+		if !k.IsKnown() {
+			t.Errorf("kind %q reported as unknown", k)
+		}
+	}
+}
+
+func TestNewCounterpart(t *testing.T) {
+	cp, err := NewCounterpart("gr16 0110 1250 0000 0001 2300 695", "Jane", "Doe", "GR")
+	if err != nil {
+		t.Fatalf("NewCounterpart: %v", err)
+	}
+	want := Counterpart{
+		Identifier: Identifier{Standard: IdentifierStandardIBAN, IBAN: "GR1601101250000000012300695"},
+		Details:    CounterpartDetails{Country: "GR", FirstName: "Jane", LastName: "Doe"},
+	}
+	if *cp != want {
+		t.Errorf("counterpart = %+v, want %+v", *cp, want)
+	}
+
+	tests := []struct {
+		name    string
+		iban    string
+		country string
+	}{
+		{"bad checksum", "GR1601101250000000012300696", "GR"},
+		{"too short", "GR16", "GR"},
+		{"empty IBAN", "", "GR"},
+		{"lower case country", "GR1601101250000000012300695", "gr"},
+		{"long country", "GR1601101250000000012300695", "GRC"},
+		{"empty country", "GR1601101250000000012300695", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewCounterpart(tt.iban, "Jane", "Doe", tt.country); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestCanPlaceOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		perms    []string
+		kyc      KYCDetails
+		accounts []Account
+		want     bool
+	}{
+		{name: "allowed", want: true},
+		{name: "no write permission", perms: []string{"read"}},
+		{name: "KYC pending", kyc: KYCDetails{State: KYCStatePending, Outcome: KYCOutcomeUnknown}},
+		{name: "KYC rejected", kyc: KYCDetails{State: KYCStateConfirmed, Outcome: string(KYCOutcomeRejected)}},
+		{name: "account pending", accounts: []Account{
+			{Address: "0x1", Chain: ChainEthereum, Network: NetworkMainnet, Currency: CurrencyEUR, State: AccountStatePending},
+		}},
+		{name: "account in other currency", accounts: []Account{
+			{Address: "0x1", Chain: ChainEthereum, Network: NetworkMainnet, Currency: CurrencyUSD, State: AccountStateApproved},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.perms == nil {
+				tt.perms = []string{"read", "write"}
+			}
+			if tt.kyc.State == "" {
+				tt.kyc = KYCDetails{State: KYCStateConfirmed, Outcome: string(KYCOutcomeApproved)}
+			}
+			if tt.accounts == nil {
+				tt.accounts = []Account{
+					{Address: "0x1", Chain: ChainEthereum, Network: NetworkMainnet, Currency: CurrencyEUR, State: AccountStateApproved},
+				}
+			}
+
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/auth/context":
+					writeJSON(t, w, &AuthContext{Profiles: []AuthProfile{{ID: "p1", Perms: tt.perms}}})
+				case "/profiles/p1":
+					writeJSON(t, w, &Profile{ID: "p1", KYC: tt.kyc, Accounts: tt.accounts})
+				default:
+					// Balances aren't checked.
+					t.Errorf("unexpected request %s", r.URL)
+				}
+			}))
+
+			ok, reason, err := c.CanPlaceOrder(context.Background(), "p1", CurrencyEUR)
+			if err != nil {
+				t.Fatalf("CanPlaceOrder: %v", err)
+			}
+			if ok != tt.want {
+				t.Errorf("CanPlaceOrder = %v (%s), want %v", ok, reason, tt.want)
+			}
+			if !ok && reason == "" {
+				t.Error("no reason given")
+			}
+		})
+	}
+}
+
+func TestPlaceOrderChecksProfilePermission(t *testing.T) {
+	var authFetches, placed int32
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth/context":
+			atomic.AddInt32(&authFetches, 1)
+			writeJSON(t, w, &AuthContext{Profiles: []AuthProfile{
+				{ID: "p1", Perms: []string{"read", "write"}},
+				{ID: "p2", Perms: []string{"read"}},
+			}})
+		case "/orders":
+			atomic.AddInt32(&placed, 1)
+			writeJSON(t, w, &Order{ID: "o1"})
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+	}))
+	cp, err := NewCounterpart("GR1601101250000000012300695", "Jane", "Doe", "GR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newRequest := func(profileID string) *PlaceOrderRequest {
+		return &PlaceOrderRequest{
+			ProfileID:   profileID,
+			Kind:        OrderKindRedeem,
+			Amount:      "10",
+			Currency:    CurrencyEUR,
+			Chain:       ChainEthereum,
+			Address:     "0x1",
+			Counterpart: cp,
+			Message:     BuildOrderMessage(CurrencyEUR, "10", cp.Identifier.IBAN, time.Now()),
+			Signature:   "0xsig",
+		}
+	}
+	ctx := context.Background()
+
+	if _, err := c.PlaceOrder(ctx, newRequest("p2")); !errors.Is(err, ErrNoWritePermission) {
+		t.Errorf("PlaceOrder on read-only profile = %v, want ErrNoWritePermission", err)
+	}
+	if _, err := c.PlaceOrder(ctx, newRequest("p1")); err != nil {
+		t.Errorf("PlaceOrder on writable profile: %v", err)
+	}
+	if _, err := c.PlaceOrder(ctx, newRequest("")); err != nil {
+		t.Errorf("PlaceOrder without profile: %v", err)
+	}
+	if n := atomic.LoadInt32(&placed); n != 2 {
+		t.Errorf("placed %d orders, want 2", n)
+	}
+	if n := atomic.LoadInt32(&authFetches); n != 1 {
+		t.Errorf("auth context fetched %d times, want once", n)
+	}
+}
+
+func TestCanPlaceOrderFailsOnAPIError(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+
+	if _, _, err := c.CanPlaceOrder(context.Background(), "p1", CurrencyEUR); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestOrderMetaDecodesTimestamps(t *testing.T) {
+	// Metadata of an order as sent by Monerium.
+	payload := `{
+		"approvedAt": "2023-05-29T12:45:30.115Z",
+		"processedAt": "2023-05-29T12:47:02.631Z",
+		"rejectedAt": null,
+		"state": "processed",
+		"placedBy": "a7e6ea3e-fe5a-11ed-a434-525f1bbe0e9e",
+		"placedAt": "2023-05-29T12:45:29.307Z",
+		"receivedAmount": "1",
+		"sentAmount": "1"
+	}`
+	var m OrderMeta
+	if err := json.Unmarshal([]byte(payload), &m); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  time.Time
+		want time.Time
+	}{
+		{"approvedAt", m.ApprovedAt, time.Date(2023, 5, 29, 12, 45, 30, 115e6, time.UTC)},
+		{"processedAt", m.ProcessedAt, time.Date(2023, 5, 29, 12, 47, 2, 631e6, time.UTC)},
+		{"placedAt", m.PlacedAt, time.Date(2023, 5, 29, 12, 45, 29, 307e6, time.UTC)},
+	}
+	for _, tt := range tests {
+		if !tt.got.Equal(tt.want) {
+			t.Errorf("%s = %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+	if !m.RejectedAt.IsZero() {
+		t.Errorf("rejectedAt = %s, want zero", m.RejectedAt)
+	}
+
+	if err := json.Unmarshal([]byte(`{"placedAt": "2023-05-29T14:45:29+02:00"}`), &m); err != nil {
+		t.Fatalf("unmarshal offset: %v", err)
+	}
+	if want := time.Date(2023, 5, 29, 12, 45, 29, 0, time.UTC); !m.PlacedAt.Equal(want) {
+		t.Errorf("placedAt with offset = %s, want %s", m.PlacedAt, want)
+	}
+}
+
+func TestPlaceOrderRequestValidateIdentifierSchemes(t *testing.T) {
+	const iban = "GR1601101250000000012300695"
+	tests := []struct {
+		name     string
+		currency Currency
+		id       Identifier
+		wantErr  string
+	}{
+		{name: "EUR with IBAN", currency: CurrencyEUR, id: Identifier{Standard: IdentifierStandardIBAN, IBAN: iban}},
+		{name: "GBP with sort code", currency: CurrencyGBP, id: Identifier{Standard: IdentifierStandardSCAN, SortCode: "200000", AccountNumber: "12345678"}},
+		{
+			name:     "IBAN with sort code",
+			currency: CurrencyEUR,
+			id:       Identifier{Standard: IdentifierStandardIBAN, IBAN: iban, SortCode: "200000", AccountNumber: "12345678"},
+			wantErr:  "iban identifier can't have sort code or account number set",
+		},
+		{
+			name:     "sort code with IBAN",
+			currency: CurrencyGBP,
+			id:       Identifier{Standard: IdentifierStandardSCAN, IBAN: iban, SortCode: "200000", AccountNumber: "12345678"},
+			wantErr:  "scan identifier can't have IBAN set",
+		},
+		{
+			name:     "EUR with sort code",
+			currency: CurrencyEUR,
+			id:       Identifier{Standard: IdentifierStandardSCAN, SortCode: "200000", AccountNumber: "12345678"},
+			wantErr:  "scan identifier requires gbp currency, got eur",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.id.IBAN
+			if target == "" {
+				target = tt.id.SortCode + tt.id.AccountNumber
+			}
+			req := &PlaceOrderRequest{
+				Kind:     OrderKindRedeem,
+				Amount:   "10",
+				Currency: tt.currency,
+				Chain:    ChainEthereum,
+				Address:  "0x1",
+				Counterpart: &Counterpart{
+					Identifier: tt.id,
+					Details:    CounterpartDetails{FirstName: "Jane", LastName: "Doe", Country: "GB"},
+				},
+				Message:   BuildOrderMessage(tt.currency, "10", target, time.Now()),
+				Signature: "0xsig",
+			}
+
+			err := req.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOrderCacheServesTerminalOrders(t *testing.T) {
+	var mu sync.Mutex
+	fetches := make(map[string]int)
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/orders/")
+		mu.Lock()
+		fetches[id]++
+		mu.Unlock()
+		state := OrderStatePending
+		if id != "pending" {
+			state = OrderState(id)
+		}
+		writeJSON(t, w, &Order{ID: id, Meta: OrderMeta{State: state}})
+	}), WithOrderCache(50*time.Millisecond))
+	ctx := context.Background()
+
+	ids := []string{"pending", string(OrderStateProcessed), string(OrderStateRejected)}
+	for i := 0; i < 3; i++ {
+		for _, id := range ids {
+			o, err := c.GetOrder(ctx, &GetOrderRequest{OrderID: id})
+			if err != nil {
+				t.Fatalf("GetOrder(%s): %v", id, err)
+			}
+			// Modifying a returned order must not affect the cache.
+			o.Meta.State = "modified"
+		}
+	}
+	mu.Lock()
+	if fetches["pending"] != 3 || fetches["processed"] != 1 || fetches["rejected"] != 1 {
+		t.Errorf("fetches = %v, want pending 3 times and terminal orders once", fetches)
+	}
+	mu.Unlock()
+
+	o, err := c.GetOrder(ctx, &GetOrderRequest{OrderID: "processed"})
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if o.Meta.State != OrderStateProcessed {
+		t.Errorf("cached state = %q, want %q", o.Meta.State, OrderStateProcessed)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := c.GetOrder(ctx, &GetOrderRequest{OrderID: "processed"}); err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if fetches["processed"] != 2 {
+		t.Errorf("processed fetched %d times, want a refetch after TTL", fetches["processed"])
+	}
+}
+
+func TestGetAllOrdersAbortsOnCancel(t *testing.T) {
+	var started int32
+	stop := make(chan struct{})
+	defer close(stop)
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/profiles" {
+			var ps []*ProfileSummary
+			for i := 0; i < 10; i++ {
+				ps = append(ps, &ProfileSummary{ID: fmt.Sprintf("p%d", i)})
+			}
+			writeJSON(t, w, ps)
+			return
+		}
+		// Stall in the middle of the response body.
+		atomic.AddInt32(&started, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"o1",`))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-stop:
+		}
+	}), WithConcurrency(2))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.GetAllOrders(ctx, nil)
+		errc <- err
+	}()
+
+	waitForConnections(t, &started, 2)
+	cancel()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetAllOrders didn't return promptly after cancel")
+	}
+	if n := atomic.LoadInt32(&started); n != 2 {
+		t.Errorf("%d profiles fetched, want only the 2 in flight", n)
+	}
+}
+
+func TestGetOrdersAbortsStalledResponseOnCancel(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	var started int32
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&started, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"o1",`))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-stop:
+		}
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrders(ctx, nil)
+		errc <- err
+	}()
+
+	waitForConnections(t, &started, 1)
+	cancel()
+	select {
+	case err := <-errc:
+		if err != context.Canceled {
+			t.Errorf("err = %v, want context.Canceled as is", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetOrders didn't return promptly after cancel")
+	}
+}
+
+func TestGetProfileOrdersPathAndQuery(t *testing.T) {
+	var (
+		path  string
+		query url.Values
+	)
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.Path, r.URL.Query()
+		writeJSON(t, w, []*Order{{ID: "o1", Profile: "p1"}})
+	}))
+	ctx := context.Background()
+
+	req := &GetOrdersRequest{ProfileID: "other", State: OrderStatePending, Memo: "INV-1"}
+	os, err := c.GetProfileOrders(ctx, "p1", req)
+	if err != nil {
+		t.Fatalf("GetProfileOrders: %v", err)
+	}
+	if len(os) != 1 || os[0].ID != "o1" {
+		t.Errorf("orders = %+v, want o1", os)
+	}
+	if path != "/orders" {
+		t.Errorf("path = %s, want /orders", path)
+	}
+	if query.Get("profile") != "p1" || query.Get("state") != string(OrderStatePending) || query.Get("memo") != "INV-1" {
+		t.Errorf("query = %v, want profile p1 with filters of req", query)
+	}
+	if req.ProfileID != "other" {
+		t.Errorf("req.ProfileID = %s, req was modified", req.ProfileID)
+	}
+
+	if _, err := c.GetProfileOrders(ctx, "p1", nil); err != nil {
+		t.Fatalf("GetProfileOrders without req: %v", err)
+	}
+	if query.Get("profile") != "p1" {
+		t.Errorf("query = %v, want profile p1", query)
+	}
+
+	if _, err := c.GetProfileOrders(ctx, "", req); err == nil {
+		t.Error("expected error for empty profileID")
+	}
+}
+
+func TestParseOrderKind(t *testing.T) {
+	tests := []struct {
+		s      string
+		want   OrderKind
+		wantOK bool
+	}{
+		{"redeem", OrderKindRedeem, true},
+		{"Issue", OrderKindIssue, true},
+		{"internal_transfer", OrderKindUnknown, false},
+		{"unknown", OrderKindUnknown, false},
+		{"", OrderKindUnknown, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, ok := ParseOrderKind(tt.s)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ParseOrderKind(%q) = %q, %v, want %q, %v", tt.s, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
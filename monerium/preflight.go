@@ -0,0 +1,57 @@
+package monerium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"nhooyr.io/websocket"
+)
+
+// Preflight checks that the Client is usable, e.g. at service startup, so that misconfiguration fails fast:
+//   - credentials are accepted and the base URL is reachable, by retrieving the auth context,
+//   - the default profile of the authenticated user is accessible,
+//   - the websocket handshake succeeds, when a websocket URL is configured or
+//     NotificationsTransportWebsocket is required.
+//
+// Every failing check contributes an error to the result, joined with errors.Join;
+// the remaining checks are skipped when the auth context can't be retrieved.
+// Scopes granted to the credentials aren't reported by the API, so they can't be checked.
+func (c *Client) Preflight(ctx context.Context) error {
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+
+	ac, err := c.GetAuthContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get auth context: %w", err)
+	}
+
+	var errs []error
+	if ac.DefaultProfileID == "" {
+		errs = append(errs, errors.New("authenticated user has no default profile"))
+	} else if _, err := c.GetProfile(ctx, &GetProfileRequest{ProfileID: ac.DefaultProfileID}); err != nil {
+		errs = append(errs, fmt.Errorf("failed to get default profile %s: %w", ac.DefaultProfileID, err))
+	}
+
+	if c.wsURL != "" || c.notifyTransport == NotificationsTransportWebsocket {
+		if err := c.preflightWebsocket(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("websocket: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// preflightWebsocket dials the websocket of order notifications and closes it right away.
+func (c *Client) preflightWebsocket(ctx context.Context) error {
+	if err := c.validateWebsocketURL(); err != nil {
+		return err
+	}
+	wc, err := c.connectWebsocket(ctx, c.wsURL+"/orders")
+	if err != nil {
+		return err
+	}
+
+	return wc.Close(websocket.StatusNormalClosure, "preflight")
+}
@@ -0,0 +1,105 @@
+package monerium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+	"nhooyr.io/websocket"
+)
+
+func TestPreflight(t *testing.T) {
+	tests := []struct {
+		name        string
+		authStatus  int
+		defaultID   string
+		profileDown bool
+		wsDown      bool
+		wsURL       string
+		wantErrs    []string
+	}{
+		{name: "ok", defaultID: "p1"},
+		{name: "credentials rejected", authStatus: http.StatusUnauthorized, defaultID: "p1", wantErrs: []string{"failed to get auth context"}},
+		{name: "no default profile", wantErrs: []string{"no default profile"}},
+		{name: "default profile inaccessible", defaultID: "p1", profileDown: true, wantErrs: []string{"failed to get default profile p1"}},
+		{name: "websocket handshake fails", defaultID: "p1", wsDown: true, wantErrs: []string{"websocket:"}},
+		{name: "invalid websocket URL", defaultID: "p1", wsURL: "http://localhost", wantErrs: []string{"websocket:", "not a ws(s) URL"}},
+		{
+			name: "several failures", defaultID: "p1", profileDown: true, wsDown: true,
+			wantErrs: []string{"failed to get default profile p1", "websocket:"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/auth/context":
+					if tt.authStatus != 0 {
+						http.Error(w, http.StatusText(tt.authStatus), tt.authStatus)
+						return
+					}
+					writeJSON(t, w, &AuthContext{UserID: "u1", DefaultProfileID: tt.defaultID})
+				case "/profiles/p1":
+					if tt.profileDown {
+						http.Error(w, "forbidden", http.StatusForbidden)
+						return
+					}
+					writeJSON(t, w, &Profile{ID: "p1"})
+				case "/orders":
+					if tt.wsDown {
+						http.Error(w, "unavailable", http.StatusServiceUnavailable)
+						return
+					}
+					conn, err := websocket.Accept(w, r, nil)
+					if err != nil {
+						return
+					}
+					conn.Read(r.Context())
+				default:
+					t.Errorf("unexpected request %s", r.URL)
+				}
+			}))
+			if tt.wsURL != "" {
+				c.wsURL = tt.wsURL
+			}
+
+			err := c.Preflight(context.Background())
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("Preflight: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Preflight = %q, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPreflightReportsTokenFailure(t *testing.T) {
+	errToken := errors.New("invalid_client")
+	c := newClient(context.Background(), "http://localhost", "", func(context.Context) oauth2.TokenSource {
+		return failingTokenSource{errToken}
+	})
+
+	if err := c.Preflight(context.Background()); !errors.Is(err, errToken) {
+		t.Errorf("Preflight = %v, want token error", err)
+	}
+}
+
+type failingTokenSource struct {
+	err error
+}
+
+func (ts failingTokenSource) Token() (*oauth2.Token, error) {
+	return nil, ts.err
+}
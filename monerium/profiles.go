@@ -5,12 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 )
 
 // GetAuthContext retrieves context of authenticated user.
+// The latest context is kept for client-side permission checks, see PlaceOrderRequest.ProfileID.
 func (c *Client) GetAuthContext(ctx context.Context) (*AuthContext, error) {
 	path := "/auth/context"
-	bs, err := c.get(ctx, path)
+	bs, err := c.get(ctx, path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -18,10 +21,80 @@ func (c *Client) GetAuthContext(ctx context.Context) (*AuthContext, error) {
 	if err = json.Unmarshal(bs, &ac); err != nil {
 		return nil, err
 	}
+	c.authContext.set(bs)
 
 	return &ac, nil
 }
 
+// authContextCache keeps the response body of the latest GetAuthContext call.
+// The body is kept rather than the context, so that every caller gets its own copy.
+type authContextCache struct {
+	mu sync.Mutex
+	bs []byte
+}
+
+// set stores bs as the latest auth context.
+func (ac *authContextCache) set(bs []byte) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	ac.bs = bs
+}
+
+// cachedAuthContext returns the auth context kept by the latest GetAuthContext call,
+// or retrieves it if there's none yet.
+func (c *Client) cachedAuthContext(ctx context.Context) (*AuthContext, error) {
+	c.authContext.mu.Lock()
+	bs := c.authContext.bs
+	c.authContext.mu.Unlock()
+	if bs == nil {
+		return c.GetAuthContext(ctx)
+	}
+
+	var ac AuthContext
+	if err := json.Unmarshal(bs, &ac); err != nil {
+		return nil, err
+	}
+
+	return &ac, nil
+}
+
+// ErrNoDefaultProfile is returned when the default profile is needed, see WithDefaultProfile,
+// but the authenticated user has none.
+var ErrNoDefaultProfile = errors.New("authenticated user has no default profile")
+
+// defaultProfile caches ID of the default profile of the authenticated user, see WithDefaultProfile.
+type defaultProfile struct {
+	enabled bool
+
+	mu sync.Mutex
+	id string
+}
+
+// profileID returns id, or the ID of the default profile if id is empty and WithDefaultProfile is enabled.
+func (c *Client) profileID(ctx context.Context, id string) (string, error) {
+	if id != "" || !c.defaultProfile.enabled {
+		return id, nil
+	}
+
+	dp := &c.defaultProfile
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+	if dp.id != "" {
+		return dp.id, nil
+	}
+	ac, err := c.GetAuthContext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get default profile: %w", err)
+	}
+	if ac.DefaultProfileID == "" {
+		return "", ErrNoDefaultProfile
+	}
+	dp.id = ac.DefaultProfileID
+
+	return dp.id, nil
+}
+
 // AuthContext represents the context of authenticated user.
 type AuthContext struct {
 	UserID           string        `json:"userId"`
@@ -40,17 +113,36 @@ type Auth struct {
 }
 
 type AuthProfile struct {
-	ID    string   `json:"id"`
-	Type  string   `json:"type"`
-	Name  string   `json:"name"`
-	Perms []string `json:"perms"`
+	ID    string      `json:"id"`
+	Type  ProfileType `json:"type"`
+	Name  string      `json:"name"`
+	Perms []string    `json:"perms"`
+}
+
+// hasPermission reports whether the authenticated user has perm on the profile identified by profileID.
+func (ac *AuthContext) hasPermission(profileID, perm string) bool {
+	for _, p := range ac.Profiles {
+		if p.ID != profileID {
+			continue
+		}
+		for _, pp := range p.Perms {
+			if pp == perm {
+				return true
+			}
+		}
+	}
+
+	return false
 }
 
 // GetProfiles retrieves all profiles summaries.
 // The summary contains information about the profile such as its kind and the permission the authenticated user has on the profiles.
-func (c *Client) GetProfiles(ctx context.Context) ([]*ProfileSummary, error) {
+func (c *Client) GetProfiles(ctx context.Context, opts ...RequestOption) ([]*ProfileSummary, error) {
+	ctx, cancel := applyRequestOptions(ctx, opts)
+	defer cancel()
+
 	path := "/profiles"
-	bs, err := c.get(ctx, path)
+	bs, err := c.get(ctx, path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -62,14 +154,95 @@ func (c *Client) GetProfiles(ctx context.Context) ([]*ProfileSummary, error) {
 	return ps, nil
 }
 
+// ProfileAccount is an Account tagged with ID of the profile owning it, see GetAllAccounts.
+// Balance is the balance of the account's currency at its address, chain and network;
+// it's nil for accounts without an address, e.g. IBAN accounts, and when balances couldn't be retrieved.
+type ProfileAccount struct {
+	ProfileID string
+	Account
+	Balance *Balance
+}
+
+// GetAllAccounts retrieves accounts of every profile accessible by the authenticated user, with their balances.
+// Profiles and their balances are retrieved concurrently, see WithConcurrency. Accounts are ordered by profile,
+// in the order of GetProfiles, and then as in Profile.Accounts.
+//
+// Failure to retrieve a profile doesn't stop the others: accounts of the retrieved profiles are returned
+// along with the failures joined by errors.Join. Accounts of a profile whose balances can't be retrieved
+// are returned without balances, the failure is joined too. Once ctx is done, its error is returned instead.
+func (c *Client) GetAllAccounts(ctx context.Context) ([]*ProfileAccount, error) {
+	ps, err := c.GetProfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pas := make([][]*ProfileAccount, len(ps))
+	errs := c.runConcurrently(ctx, len(ps), func(i int) error {
+		p, err := c.GetProfile(ctx, &GetProfileRequest{ProfileID: ps[i].ID})
+		if err != nil {
+			return fmt.Errorf("failed to get profile %s: %w", ps[i].ID, err)
+		}
+		for _, a := range p.Accounts {
+			pas[i] = append(pas[i], &ProfileAccount{ProfileID: ps[i].ID, Account: a})
+		}
+
+		pbs, err := c.GetBalancesForProfile(ctx, &GetBalancesForProfileRequest{ProfileID: ps[i].ID})
+		if err != nil {
+			return fmt.Errorf("failed to get balances of profile %s: %w", ps[i].ID, err)
+		}
+		for _, pa := range pas[i] {
+			pa.Balance = accountBalance(&pa.Account, pbs)
+		}
+
+		return nil
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var all []*ProfileAccount
+	for _, as := range pas {
+		all = append(all, as...)
+	}
+
+	return all, errors.Join(errs...)
+}
+
+// accountBalance returns the balance of a's currency reported in pbs for a's address, chain and network,
+// or nil if there's none.
+func accountBalance(a *Account, pbs []*ProfileBalance) *Balance {
+	if a.Address == "" {
+		return nil
+	}
+	for _, pb := range pbs {
+		if !strings.EqualFold(pb.Address, a.Address) || pb.Chain != a.Chain || pb.Network != a.Network {
+			continue
+		}
+		for _, b := range pb.Balances {
+			if strings.EqualFold(b.Currency, string(a.Currency)) {
+				return b
+			}
+		}
+	}
+
+	return nil
+}
+
 // GetProfile retrieves a single profile details.
 func (c *Client) GetProfile(ctx context.Context, req *GetProfileRequest) (*Profile, error) {
+	if req != nil {
+		id, err := c.profileID(ctx, req.ProfileID)
+		if err != nil {
+			return nil, err
+		}
+		req = &GetProfileRequest{ProfileID: id}
+	}
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
 
 	path := fmt.Sprintf("/profiles/%s", req.ProfileID)
-	bs, err := c.get(ctx, path)
+	bs, err := c.get(ctx, path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -80,10 +253,12 @@ func (c *Client) GetProfile(ctx context.Context, req *GetProfileRequest) (*Profi
 	return &pr, nil
 }
 
+// GetProfileRequest contains data needed for making the request.
 type GetProfileRequest struct {
 	ProfileID string
 }
 
+// Validate checks GetProfileRequest.
 func (r *GetProfileRequest) Validate() error {
 	if r == nil {
 		return errors.New("GetProfileRequest is required")
@@ -97,10 +272,24 @@ func (r *GetProfileRequest) Validate() error {
 
 // ProfileSummary contains auth related information about the profile: type and permissions.
 type ProfileSummary struct {
-	ID          string   `json:"id,omitempty"`
-	Name        string   `json:"name,omitempty"`
-	Type        string   `json:"type,omitempty"`
-	Permissions []string `json:"perms,omitempty"`
+	ID          string      `json:"id,omitempty"`
+	Name        string      `json:"name,omitempty"`
+	Type        ProfileType `json:"type,omitempty"`
+	Permissions []string    `json:"perms,omitempty"`
+}
+
+// ProfileType represents the type of a profile.
+// Types unknown to the SDK are kept verbatim, use IsKnown to detect them.
+type ProfileType string
+
+const (
+	ProfileTypePersonal  ProfileType = "personal"
+	ProfileTypeCorporate ProfileType = "corporate"
+)
+
+// IsKnown reports whether t is one of profile types known to the SDK.
+func (t ProfileType) IsKnown() bool {
+	return t == ProfileTypePersonal || t == ProfileTypeCorporate
 }
 
 // Profile contains general information about the profile: KYC details and linked accounts.
@@ -111,13 +300,113 @@ type Profile struct {
 	Accounts []Account  `json:"accounts,omitempty"`
 }
 
+// ActiveAccounts returns accounts of the profile in AccountStateApproved.
+func (p *Profile) ActiveAccounts() []Account {
+	var as []Account
+	for _, a := range p.Accounts {
+		if a.State == AccountStateApproved {
+			as = append(as, a)
+		}
+	}
+
+	return as
+}
+
+// AccountsByCurrency returns accounts of the profile in currency c, regardless of their state.
+func (p *Profile) AccountsByCurrency(c Currency) []Account {
+	var as []Account
+	for _, a := range p.Accounts {
+		if a.Currency == c {
+			as = append(as, a)
+		}
+	}
+
+	return as
+}
+
+// DefaultAccount returns the account of the profile to be shown as the primary deposit address
+// for currency on chain. The API doesn't mark a default account, so the rule is: the first account
+// in Profile.Accounts in currency, on chain, linked to an address and in AccountStateApproved,
+// i.e. it follows the order of accounts returned by the API.
+// It reports false if there's no such account.
+func (p *Profile) DefaultAccount(currency Currency, chain Chain) (*Account, bool) {
+	for _, a := range p.Accounts {
+		if a.Currency == currency && a.Chain == chain && a.Address != "" && a.State == AccountStateApproved {
+			return &a, true
+		}
+	}
+
+	return nil, false
+}
+
+// AccountByIBAN returns the account of the profile with iban, regardless of its state.
+// IBANs are compared ignoring spaces and case.
+func (p *Profile) AccountByIBAN(iban string) (Account, bool) {
+	iban = normalizeIBAN(iban)
+	if iban == "" {
+		return Account{}, false
+	}
+	for _, a := range p.Accounts {
+		if normalizeIBAN(a.IBAN) == iban {
+			return a, true
+		}
+	}
+
+	return Account{}, false
+}
+
+// FindAccountByIBAN retrieves the account of the profile identified by profileID with iban,
+// e.g. to match the IBAN of an incoming SEPA transfer. See Profile.AccountByIBAN.
+// It returns an error wrapping ErrNotFound if the profile has no such account.
+func (c *Client) FindAccountByIBAN(ctx context.Context, profileID, iban string) (*Account, error) {
+	p, err := c.GetProfile(ctx, &GetProfileRequest{ProfileID: profileID})
+	if err != nil {
+		return nil, err
+	}
+	a, ok := p.AccountByIBAN(iban)
+	if !ok {
+		return nil, fmt.Errorf("account with IBAN %s: %w", normalizeIBAN(iban), ErrNotFound)
+	}
+
+	return &a, nil
+}
+
+// GetProfileAddresses retrieves accounts of the profile linked to blockchain addresses,
+// i.e. accounts with non-empty Address. The accounts are extracted from GetProfile.
+func (c *Client) GetProfileAddresses(ctx context.Context, profileID string) ([]Account, error) {
+	p, err := c.GetProfile(ctx, &GetProfileRequest{ProfileID: profileID})
+	if err != nil {
+		return nil, err
+	}
+
+	var as []Account
+	for _, a := range p.Accounts {
+		if a.Address != "" {
+			as = append(as, a)
+		}
+	}
+
+	return as, nil
+}
+
 // AddAddressToProfile links given blockchain address (wallet) and create an account for Monerium tokens.
 func (c *Client) AddAddressToProfile(ctx context.Context, req *AddAddressToProfileRequest) (*Profile, error) {
-	if err := req.Validate(); err != nil {
-		return nil, err
+	if req != nil && req.ProfileID == "" {
+		id, err := c.profileID(ctx, req.ProfileID)
+		if err != nil {
+			return nil, err
+		}
+		r := *req
+		r.ProfileID = id
+		req = &r
 	}
 
-	path := fmt.Sprintf("/profiles/%s/addresses", req.ProfileID)
+	// A nil req is rejected by post, which validates it.
+	var profileID string
+	if req != nil {
+		profileID = req.ProfileID
+	}
+	path := fmt.Sprintf("/profiles/%s/addresses", profileID)
 	bs, err := c.post(ctx, path, req)
 	if err != nil {
 		return nil, err
@@ -130,6 +419,14 @@ func (c *Client) AddAddressToProfile(ctx context.Context, req *AddAddressToProfi
 	return &p, nil
 }
 
+// AddAddressToProfileRequest contains the address to be linked, proved by Signature of Message,
+// and accounts to be created for it.
+//
+// A single request may link the address on several chains, with one entry in Accounts per currency,
+// chain and network, e.g. EUR on ethereum, polygon and gnosis. Message is signed once: the signature
+// of an externally owned account proves control of the address on every chain. Only Currency, Chain
+// and Network of Accounts need to be set. Smart contract wallets verify signatures on-chain, so they
+// must be deployed on every chain listed, or linked with separate requests.
 type AddAddressToProfileRequest struct {
 	ProfileID string    `json:"-"`
 	Address   string    `json:"address"`
@@ -138,6 +435,7 @@ type AddAddressToProfileRequest struct {
 	Accounts  []Account `json:"accounts"`
 }
 
+// Validate checks AddAddressToProfileRequest.
 func (r *AddAddressToProfileRequest) Validate() error {
 	if r == nil {
 		return errors.New("AddAddressToProfileRequest is required")
@@ -145,6 +443,26 @@ func (r *AddAddressToProfileRequest) Validate() error {
 	if r.ProfileID == "" {
 		return errors.New("empty profileID")
 	}
+	if r.Address == "" {
+		return errors.New("empty address")
+	}
+	if r.Message == "" || r.Signature == "" {
+		return errors.New("message and signature are required")
+	}
+	if len(r.Accounts) == 0 {
+		return errors.New("no accounts to create")
+	}
+	seen := make(map[Account]bool, len(r.Accounts))
+	for i, a := range r.Accounts {
+		if a.Currency == "" || a.Chain == "" || a.Network == "" {
+			return fmt.Errorf("account %d: currency, chain and network are required", i)
+		}
+		k := Account{Currency: a.Currency, Chain: a.Chain, Network: a.Network}
+		if seen[k] {
+			return fmt.Errorf("account %d: duplicate %s on %s %s", i, a.Currency, a.Chain, a.Network)
+		}
+		seen[k] = true
+	}
 
 	return nil
 }
@@ -183,13 +501,58 @@ const (
 
 // Account represents an account in Monerium system.
 type Account struct {
-	Address       string   `json:"address,omitempty"`
-	Chain         Chain    `json:"chain,omitempty"`
-	Network       Network  `json:"network,omitempty"`
-	Currency      Currency `json:"currency,omitempty"`
-	Standard      string   `json:"standard,omitempty"`
-	IBAN          string   `json:"iban,omitempty"`
-	State         string   `json:"state,omitempty"`
-	SortCode      string   `json:"sortCode,omitempty"`
-	AccountNumber string   `json:"accountNumber,omitempty"`
+	Address       string       `json:"address,omitempty"`
+	Chain         Chain        `json:"chain,omitempty"`
+	Network       Network      `json:"network,omitempty"`
+	Currency      Currency     `json:"currency,omitempty"`
+	Standard      string       `json:"standard,omitempty"`
+	IBAN          string       `json:"iban,omitempty"`
+	State         AccountState `json:"state,omitempty"`
+	SortCode      string       `json:"sortCode,omitempty"`
+	AccountNumber string       `json:"accountNumber,omitempty"`
 }
+
+// HasIBAN reports whether the account is a fiat account reachable by SEPA transfers, i.e. it has an IBAN.
+func (a Account) HasIBAN() bool {
+	return a.IBAN != ""
+}
+
+// IsOnChain reports whether the account holds tokens on a blockchain, i.e. it's linked to an address on a chain.
+// An account can be both, when the tokens of an address are backed by its IBAN.
+func (a Account) IsOnChain() bool {
+	return a.Address != "" && a.Chain != ""
+}
+
+// Validate checks consistency of the account: an account with an IBAN must have a valid one,
+// a currency, and the iban standard if the standard is set.
+func (a Account) Validate() error {
+	if !a.HasIBAN() {
+		return nil
+	}
+	if err := validateIBAN(normalizeIBAN(a.IBAN)); err != nil {
+		return err
+	}
+	if a.Currency == "" {
+		return fmt.Errorf("account with IBAN %s has no currency", a.IBAN)
+	}
+	if a.Standard != "" && a.Standard != string(IdentifierStandardIBAN) {
+		return fmt.Errorf("account with IBAN %s has %s standard", a.IBAN, a.Standard)
+	}
+
+	return nil
+}
+
+// AccountState represents the state of an Account.
+type AccountState string
+
+const (
+	// AccountStateRequested means the account has been requested but not processed yet.
+	AccountStateRequested AccountState = "requested"
+	// AccountStatePending means the account is being set up.
+	AccountStatePending AccountState = "pending"
+	// AccountStateApproved means the account is active and can be used.
+	AccountStateApproved AccountState = "approved"
+)
+
+// permWrite is the permission needed to act on behalf of a profile, e.g. place orders.
+const permWrite = "write"
@@ -0,0 +1,297 @@
+package monerium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestGetAllAccountsIncludesBalances(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/profiles":
+			writeJSON(t, w, []*ProfileSummary{{ID: "p1"}, {ID: "p2"}})
+		case "/profiles/p1":
+			writeJSON(t, w, &Profile{ID: "p1", Accounts: []Account{
+				{Currency: CurrencyEUR, IBAN: "GR1601101250000000012300695"},
+				{Address: "0xAbC", Chain: ChainEthereum, Network: NetworkMainnet, Currency: CurrencyEUR},
+				{Address: "0xabc", Chain: ChainPolygon, Network: NetworkMainnet, Currency: CurrencyEUR},
+			}})
+		case "/profiles/p1/balances":
+			writeJSON(t, w, []*ProfileBalance{{
+				ProfileID: "p1", Address: "0xabc", Chain: ChainEthereum, Network: NetworkMainnet,
+				Balances: []*Balance{{Currency: "eur", Amount: "12.5"}},
+			}})
+		case "/profiles/p2":
+			writeJSON(t, w, &Profile{ID: "p2", Accounts: []Account{
+				{Address: "0xdef", Chain: ChainGnosis, Network: NetworkMainnet, Currency: CurrencyEUR},
+			}})
+		default:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+
+	pas, err := c.GetAllAccounts(context.Background())
+	if err == nil {
+		t.Error("expected error for balances of p2")
+	}
+	if len(pas) != 4 {
+		t.Fatalf("got %d accounts, want 4", len(pas))
+	}
+
+	if pas[0].ProfileID != "p1" || pas[0].Balance != nil {
+		t.Errorf("IBAN account = %+v, want p1 without balance", pas[0])
+	}
+	if b := pas[1].Balance; b == nil || b.Amount != "12.5" {
+		t.Errorf("ethereum account balance = %+v, want 12.5", b)
+	}
+	if pas[2].Balance != nil {
+		t.Errorf("polygon account balance = %+v, want none", pas[2].Balance)
+	}
+	if pas[3].ProfileID != "p2" || pas[3].Balance != nil {
+		t.Errorf("p2 account = %+v, want p2 without balance", pas[3])
+	}
+}
+
+func TestResolvePlacedBy(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, &AuthContext{UserID: "u1", Name: "Jane Doe", Email: "jane@example.com"})
+	}))
+	ctx := context.Background()
+
+	p, err := c.ResolvePlacedBy(ctx, &Order{Meta: OrderMeta{PlacedBy: "u1"}})
+	if err != nil {
+		t.Fatalf("ResolvePlacedBy: %v", err)
+	}
+	if *p != (OrderPlacer{UserID: "u1", Name: "Jane Doe", Email: "jane@example.com"}) {
+		t.Errorf("placer = %+v", p)
+	}
+
+	if _, err = c.ResolvePlacedBy(ctx, &Order{Meta: OrderMeta{PlacedBy: "u2"}}); !errors.Is(err, ErrPlacerNotAccessible) {
+		t.Errorf("other placer: err = %v, want ErrPlacerNotAccessible", err)
+	}
+	if _, err = c.ResolvePlacedBy(ctx, &Order{}); err == nil {
+		t.Error("order without placer: expected error")
+	}
+}
+
+func TestProfileTypeDecoding(t *testing.T) {
+	tests := []struct {
+		raw   string
+		want  ProfileType
+		known bool
+	}{
+		{"personal", ProfileTypePersonal, true},
+		{"corporate", ProfileTypeCorporate, true},
+		{"partner", "partner", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		payload := fmt.Sprintf(`{"id":"p1","type":%q}`, tt.raw)
+		var ps ProfileSummary
+		if err := json.Unmarshal([]byte(payload), &ps); err != nil {
+			t.Fatalf("unmarshal ProfileSummary %s: %v", payload, err)
+		}
+		var ap AuthProfile
+		if err := json.Unmarshal([]byte(payload), &ap); err != nil {
+			t.Fatalf("unmarshal AuthProfile %s: %v", payload, err)
+		}
+
+		for _, got := range []ProfileType{ps.Type, ap.Type} {
+			if got != tt.want {
+				t.Errorf("%q: type = %q, want %q", tt.raw, got, tt.want)
+			}
+			if got.IsKnown() != tt.known {
+				t.Errorf("%q: IsKnown = %v, want %v", tt.raw, got.IsKnown(), tt.known)
+			}
+		}
+	}
+}
+
+func TestAddAddressToProfileLinksSeveralChains(t *testing.T) {
+	req := &AddAddressToProfileRequest{
+		ProfileID: "p1",
+		Address:   "0x59cFC8E5b8b8d8a0d2a4F0A4c2e8e1D1d1B8A2a4",
+		Message:   "I hereby declare that I am the address owner.",
+		Signature: "0xsig",
+		Accounts: []Account{
+			{Currency: CurrencyEUR, Chain: ChainEthereum, Network: NetworkMainnet},
+			{Currency: CurrencyEUR, Chain: ChainPolygon, Network: NetworkMainnet},
+			{Currency: CurrencyEUR, Chain: ChainGnosis, Network: NetworkMainnet},
+		},
+	}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/profiles/p1/addresses" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		if _, ok := body["ProfileID"]; ok {
+			t.Error("profile ID sent in body")
+		}
+		want := `[{"chain":"ethereum","currency":"eur","network":"mainnet"},` +
+			`{"chain":"polygon","currency":"eur","network":"mainnet"},` +
+			`{"chain":"gnosis","currency":"eur","network":"mainnet"}]`
+		if got, _ := json.Marshal(body["accounts"]); string(got) != want {
+			t.Errorf("accounts = %s, want %s", got, want)
+		}
+		if body["address"] != req.Address || body["message"] != req.Message || body["signature"] != req.Signature {
+			t.Errorf("body = %v", body)
+		}
+		writeJSON(t, w, &Profile{ID: "p1"})
+	}))
+
+	if _, err := c.AddAddressToProfile(context.Background(), req); err != nil {
+		t.Fatalf("AddAddressToProfile: %v", err)
+	}
+}
+
+func TestAddAddressToProfileRequestValidate(t *testing.T) {
+	eth := Account{Currency: CurrencyEUR, Chain: ChainEthereum, Network: NetworkMainnet}
+	tests := []struct {
+		name     string
+		accounts []Account
+		wantErr  bool
+	}{
+		{"one chain", []Account{eth}, false},
+		{"same currency on several chains", []Account{eth, {Currency: CurrencyEUR, Chain: ChainGnosis, Network: NetworkMainnet}}, false},
+		{"several currencies on one chain", []Account{eth, {Currency: CurrencyUSD, Chain: ChainEthereum, Network: NetworkMainnet}}, false},
+		{"no accounts", nil, true},
+		{"duplicate", []Account{eth, eth}, true},
+		{"missing network", []Account{{Currency: CurrencyEUR, Chain: ChainPolygon}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &AddAddressToProfileRequest{ProfileID: "p1", Address: "0x1", Message: "msg", Signature: "0xsig", Accounts: tt.accounts}
+			if err := req.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFindAccountByIBAN(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, &Profile{ID: "p1", Accounts: []Account{
+			{Address: "0x1", Chain: ChainEthereum, Network: NetworkMainnet, Currency: CurrencyEUR},
+			{Currency: CurrencyEUR, IBAN: "GR16 0110 1250 0000 0001 2300 695", State: AccountStateApproved},
+			{Currency: CurrencyEUR, IBAN: "is140159260076545510730339", State: AccountStatePending},
+		}})
+	}))
+	ctx := context.Background()
+
+	tests := []struct {
+		iban string
+		want string
+	}{
+		{"GR1601101250000000012300695", "GR16 0110 1250 0000 0001 2300 695"},
+		{"gr16 0110 1250 0000 0001 2300 695", "GR16 0110 1250 0000 0001 2300 695"},
+		{"IS14 0159 2600 7654 5510 7303 39", "is140159260076545510730339"},
+		{"IS140159260076545510730339", "is140159260076545510730339"},
+	}
+	for _, tt := range tests {
+		a, err := c.FindAccountByIBAN(ctx, "p1", tt.iban)
+		if err != nil {
+			t.Fatalf("FindAccountByIBAN(%q): %v", tt.iban, err)
+		}
+		if a.IBAN != tt.want {
+			t.Errorf("FindAccountByIBAN(%q) = %s, want %s", tt.iban, a.IBAN, tt.want)
+		}
+	}
+
+	for _, iban := range []string{"DE89370400440532013000", ""} {
+		if _, err := c.FindAccountByIBAN(ctx, "p1", iban); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindAccountByIBAN(%q) = %v, want ErrNotFound", iban, err)
+		}
+	}
+}
+
+func TestDefaultProfileIsFilledLazily(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		paths       []string
+		authFetches int
+	)
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/auth/context":
+			authFetches++
+			if authFetches == 1 {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			writeJSON(t, w, &AuthContext{UserID: "u1", DefaultProfileID: "p1"})
+		case strings.HasSuffix(r.URL.Path, "/balances"):
+			paths = append(paths, r.URL.Path)
+			writeJSON(t, w, []*ProfileBalance{})
+		default:
+			paths = append(paths, r.URL.Path)
+			writeJSON(t, w, &Profile{ID: strings.TrimPrefix(r.URL.Path, "/profiles/")})
+		}
+	}), WithDefaultProfile())
+	ctx := context.Background()
+
+	// A failed lookup isn't cached.
+	if _, err := c.GetProfile(ctx, &GetProfileRequest{}); err == nil {
+		t.Fatal("expected error of failed lookup")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetProfile(ctx, &GetProfileRequest{}); err != nil {
+				t.Errorf("GetProfile: %v", err)
+			}
+			if _, err := c.GetBalancesForProfile(ctx, &GetBalancesForProfileRequest{}); err != nil {
+				t.Errorf("GetBalancesForProfile: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := c.GetProfile(ctx, &GetProfileRequest{ProfileID: "p2"}); err != nil {
+		t.Fatalf("GetProfile: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if authFetches != 2 {
+		t.Errorf("auth context fetched %d times, want 2: the failed lookup and one more", authFetches)
+	}
+	sort.Strings(paths)
+	want := "[/profiles/p1 /profiles/p1 /profiles/p1 /profiles/p1 /profiles/p1/balances /profiles/p1/balances /profiles/p1/balances /profiles/p1/balances /profiles/p2]"
+	if fmt.Sprint(paths) != want {
+		t.Errorf("requested %v, want %s", paths, want)
+	}
+}
+
+func TestDefaultProfileMissing(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth/context" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		writeJSON(t, w, &AuthContext{UserID: "u1"})
+	}), WithDefaultProfile())
+
+	if _, err := c.GetProfile(context.Background(), &GetProfileRequest{}); !errors.Is(err, ErrNoDefaultProfile) {
+		t.Errorf("err = %v, want ErrNoDefaultProfile", err)
+	}
+
+	c = newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+	}))
+	if _, err := c.GetProfile(context.Background(), &GetProfileRequest{}); err == nil {
+		t.Error("expected error for empty profileID without WithDefaultProfile")
+	}
+}
@@ -0,0 +1,112 @@
+package monerium
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit represents the rate limit state reported by the API in X-RateLimit-Limit, X-RateLimit-Remaining
+// and X-RateLimit-Reset headers of the latest response, see Client.RateLimit.
+type RateLimit struct {
+	// Limit is the number of requests allowed in the current window.
+	Limit int
+	// Remaining is the number of requests left in the current window.
+	Remaining int
+	// Reset is when the current window ends.
+	Reset time.Time
+}
+
+// rateLimiter tracks RateLimit of the latest response carrying rate limit headers.
+type rateLimiter struct {
+	mu    sync.Mutex
+	rl    RateLimit
+	known bool
+}
+
+// update updates the tracked state from h. Responses without rate limit headers are ignored,
+// except 429 responses with Retry-After header, which exhaust the window until then.
+func (l *rateLimiter) update(statusCode int, h http.Header, now time.Time) {
+	rl, ok := parseRateLimit(h, now)
+	if statusCode == http.StatusTooManyRequests {
+		if d, retryAfter := parseSeconds(h.Get("Retry-After")); retryAfter {
+			rl.Remaining = 0
+			rl.Reset = now.Add(d)
+			ok = true
+		}
+	}
+	if !ok {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rl = rl
+	l.known = true
+}
+
+// parseRateLimit parses rate limit headers of h. X-RateLimit-Reset is either a number of seconds
+// until the reset or a Unix timestamp, told apart by magnitude.
+func parseRateLimit(h http.Header, now time.Time) (RateLimit, bool) {
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	rl := RateLimit{Remaining: remaining}
+	rl.Limit, _ = strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		if reset > 1e9 {
+			rl.Reset = time.Unix(reset, 0)
+		} else {
+			rl.Reset = now.Add(time.Duration(reset) * time.Second)
+		}
+	}
+
+	return rl, true
+}
+
+// parseSeconds parses a non-negative number of seconds, as used by Retry-After header.
+func parseSeconds(s string) (time.Duration, bool) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+
+	return time.Duration(n) * time.Second, true
+}
+
+// RateLimit returns the rate limit state reported by the latest response carrying it.
+// It reports false if no response carried it yet, e.g. because the API doesn't send rate limit headers.
+func (c *Client) RateLimit() (RateLimit, bool) {
+	c.rateLimiter.mu.Lock()
+	defer c.rateLimiter.mu.Unlock()
+
+	return c.rateLimiter.rl, c.rateLimiter.known
+}
+
+// WaitForRateLimit blocks until the current rate limit window resets if fewer requests remain in it
+// than the threshold set by WithRateLimitThreshold, 1 by default, i.e. none remain,
+// so that batch jobs can pace themselves instead of running into 429 responses.
+// It returns immediately if enough requests remain or the rate limit state is unknown, see RateLimit,
+// and with ctx.Err() if ctx is done first.
+//
+// The state comes from the latest response, so goroutines calling WaitForRateLimit concurrently
+// may still exceed the limit; combine it with a RetryPolicy, which retries 429 responses.
+func (c *Client) WaitForRateLimit(ctx context.Context) error {
+	threshold := c.rateLimitThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+	rl, ok := c.RateLimit()
+	if !ok || rl.Remaining >= threshold {
+		return nil
+	}
+	d := time.Until(rl.Reset)
+	if d <= 0 {
+		return nil
+	}
+
+	return sleep(ctx, d)
+}
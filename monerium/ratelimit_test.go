@@ -0,0 +1,165 @@
+package monerium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterUpdate(t *testing.T) {
+	now := time.Date(2023, 5, 29, 12, 0, 0, 0, time.UTC)
+	header := func(kv ...string) http.Header {
+		h := http.Header{}
+		for i := 0; i < len(kv); i += 2 {
+			h.Set(kv[i], kv[i+1])
+		}
+		return h
+	}
+
+	tests := []struct {
+		name   string
+		status int
+		h      http.Header
+		want   RateLimit
+		known  bool
+	}{
+		{"no headers", http.StatusOK, header(), RateLimit{}, false},
+		{
+			"reset in seconds", http.StatusOK,
+			header("X-RateLimit-Limit", "100", "X-RateLimit-Remaining", "7", "X-RateLimit-Reset", "30"),
+			RateLimit{Limit: 100, Remaining: 7, Reset: now.Add(30 * time.Second)}, true,
+		},
+		{
+			"reset as Unix time", http.StatusOK,
+			header("X-RateLimit-Remaining", "0", "X-RateLimit-Reset", strconv.FormatInt(now.Add(time.Minute).Unix(), 10)),
+			RateLimit{Remaining: 0, Reset: now.Add(time.Minute)}, true,
+		},
+		{
+			"429 with Retry-After", http.StatusTooManyRequests,
+			header("Retry-After", "5"),
+			RateLimit{Remaining: 0, Reset: now.Add(5 * time.Second)}, true,
+		},
+		{"429 without Retry-After", http.StatusTooManyRequests, header(), RateLimit{}, false},
+		{"malformed remaining", http.StatusOK, header("X-RateLimit-Remaining", "many"), RateLimit{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l rateLimiter
+			l.update(tt.status, tt.h, now)
+			if l.known != tt.known || l.rl.Limit != tt.want.Limit || l.rl.Remaining != tt.want.Remaining || !l.rl.Reset.Equal(tt.want.Reset) {
+				t.Errorf("state = %+v (known: %v), want %+v (known: %v)", l.rl, l.known, tt.want, tt.known)
+			}
+		})
+	}
+}
+
+func TestWaitForRateLimit(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		remaining = "5"
+	)
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		w.Header().Set("X-RateLimit-Remaining", remaining)
+		mu.Unlock()
+		w.Header().Set("X-RateLimit-Reset", "60")
+		writeJSON(t, w, []*Token{})
+	}))
+	ctx := context.Background()
+
+	if _, ok := c.RateLimit(); ok {
+		t.Error("rate limit known before any response")
+	}
+	if err := c.WaitForRateLimit(ctx); err != nil {
+		t.Errorf("WaitForRateLimit with unknown state: %v", err)
+	}
+
+	if _, err := c.GetTokens(ctx); err != nil {
+		t.Fatalf("GetTokens: %v", err)
+	}
+	if rl, ok := c.RateLimit(); !ok || rl.Remaining != 5 {
+		t.Errorf("RateLimit = %+v, %v, want 5 remaining", rl, ok)
+	}
+	if err := c.WaitForRateLimit(ctx); err != nil {
+		t.Errorf("WaitForRateLimit with requests remaining: %v", err)
+	}
+
+	mu.Lock()
+	remaining = "0"
+	mu.Unlock()
+	if _, err := c.GetTokens(ctx); err != nil {
+		t.Fatalf("GetTokens: %v", err)
+	}
+	wctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := c.WaitForRateLimit(wctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitForRateLimit with exhausted window = %v, want to block until ctx is done", err)
+	}
+}
+
+func TestWaitForRateLimitThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		remaining string
+		wantWait  bool
+	}{
+		{"default with one remaining", 0, "1", false},
+		{"default with none remaining", 0, "0", true},
+		{"remaining at threshold", 3, "3", false},
+		{"remaining below threshold", 3, "2", true},
+		{"non-positive threshold", -1, "0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []ClientOption
+			if tt.threshold != 0 {
+				opts = append(opts, WithRateLimitThreshold(tt.threshold))
+			}
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-RateLimit-Remaining", tt.remaining)
+				w.Header().Set("X-RateLimit-Reset", "60")
+				writeJSON(t, w, []*Token{})
+			}), opts...)
+			if _, err := c.GetTokens(context.Background()); err != nil {
+				t.Fatalf("GetTokens: %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			err := c.WaitForRateLimit(ctx)
+			if gotWait := errors.Is(err, context.DeadlineExceeded); gotWait != tt.wantWait {
+				t.Errorf("WaitForRateLimit = %v, want waiting: %v", err, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestRateLimitTrackingIsConcurrencySafe(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "10")
+		w.Header().Set("X-RateLimit-Reset", "1")
+		writeJSON(t, w, []*Token{})
+	}))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetTokens(ctx); err != nil {
+				t.Errorf("GetTokens: %v", err)
+			}
+			if err := c.WaitForRateLimit(ctx); err != nil {
+				t.Errorf("WaitForRateLimit: %v", err)
+			}
+			c.RateLimit()
+		}()
+	}
+	wg.Wait()
+}
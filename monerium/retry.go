@@ -0,0 +1,110 @@
+package monerium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// RetryPolicy describes how failed calls are retried.
+// A call is retried on network errors and on 429 and 5xx responses.
+//
+// If the context of a call has a deadline, every attempt gets a share of the remaining time,
+// so that retries still fit within the deadline, see attemptContext. An attempt running out of
+// its share is retried like a network error.
+//
+// Retried POST calls, e.g. PlaceOrder, might be processed by Monerium more than once
+// if the failure happened after the request reached the server. Use WithRetryPolicy
+// with NoRetry to disable retries of such calls when a global policy is set via WithRetry.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	// Values lower than 2 disable retries.
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry. It doubles with every next retry.
+	MinBackoff time.Duration
+	// MaxBackoff caps the delay between retries. Zero means no cap.
+	MaxBackoff time.Duration
+}
+
+// NoRetry is a RetryPolicy which disables retries.
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// backoff returns the delay before the retry following attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	return d
+}
+
+type retryPolicyKey struct{}
+
+// WithRetryPolicy returns a copy of ctx carrying p, which is used for calls made with the context.
+// It takes precedence over the policy set with WithRetry, e.g. to disable retries of a time-sensitive
+// PlaceOrder or to retry balance reads more aggressively.
+func WithRetryPolicy(ctx context.Context, p RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, p)
+}
+
+// retryPolicy returns the retry policy of a call made with ctx.
+func (c *Client) retryPolicy(ctx context.Context) RetryPolicy {
+	if p, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy); ok {
+		return p
+	}
+
+	return c.retry
+}
+
+// attemptContext returns a context of an attempt, given there are attempts left including this one.
+// If ctx has a deadline, the time remaining until it is split evenly among the attempts left, so that
+// a stalled attempt doesn't use up the time needed by retries: with 3 attempts and 9s left,
+// the first attempt gets 3s, and the second one half of what's left after the first attempt and backoff.
+// The last attempt gets all the remaining time.
+func attemptContext(ctx context.Context, attempts int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || attempts < 2 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, time.Until(deadline)/time.Duration(attempts))
+}
+
+// isRetryableStatus reports whether a response with status code might succeed when retried.
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// isRetryableError reports whether a failed request might succeed when retried.
+// Requests are not retried once ctx is done or when the token can't be retrieved.
+func isRetryableError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var re *oauth2.RetrieveError
+
+	return !errors.As(err, &re)
+}
+
+// sleep waits for d or until ctx is done, whichever happens first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,51 @@
+package monerium
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPerCallRetryPolicyOverridesClientPolicy(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}), WithRetry(RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}))
+
+	tests := []struct {
+		name     string
+		call     func(ctx context.Context) error
+		expected int32
+	}{
+		{"client policy", func(ctx context.Context) error {
+			_, err := c.GetBalances(ctx)
+			return err
+		}, 3},
+		{"context override", func(ctx context.Context) error {
+			_, err := c.GetBalances(WithRetryPolicy(ctx, NoRetry))
+			return err
+		}, 1},
+		{"call option override", func(ctx context.Context) error {
+			_, err := c.GetBalances(ctx, WithCallRetryPolicy(RetryPolicy{MaxAttempts: 5, MinBackoff: time.Millisecond}))
+			return err
+		}, 5},
+		{"call option wins over context", func(ctx context.Context) error {
+			_, err := c.GetBalances(WithRetryPolicy(ctx, NoRetry), WithCallRetryPolicy(RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}))
+			return err
+		}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			if err := tt.call(context.Background()); err == nil {
+				t.Fatal("expected error")
+			}
+			if n := atomic.LoadInt32(&calls); n != tt.expected {
+				t.Errorf("got %d attempts, want %d", n, tt.expected)
+			}
+		})
+	}
+}
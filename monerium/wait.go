@@ -0,0 +1,255 @@
+package monerium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WaitOption represents a configurable option of waiting for orders.
+type WaitOption func(*waitConfig)
+
+// waitConfig contains settings of waiting for orders.
+type waitConfig struct {
+	interval  time.Duration
+	profileID string
+}
+
+// WithWaitInterval sets interval of polling orders. It defaults to the interval set by WithPollInterval.
+// The interval must be positive, waiting fails otherwise.
+func WithWaitInterval(d time.Duration) WaitOption {
+	return func(c *waitConfig) {
+		c.interval = d
+	}
+}
+
+// newWaitConfig creates waitConfig from opts, checking that the polling interval is positive.
+func (c *Client) newWaitConfig(opts []WaitOption) (waitConfig, error) {
+	cfg := waitConfig{interval: c.pollInterval}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if cfg.interval <= 0 {
+		return cfg, fmt.Errorf("non-positive poll interval: %s", cfg.interval)
+	}
+
+	return cfg, nil
+}
+
+// WithWaitProfileID narrows polled orders to the profile identified by profileID.
+func WithWaitProfileID(profileID string) WaitOption {
+	return func(c *waitConfig) {
+		c.profileID = profileID
+	}
+}
+
+// WaitForOrders polls orders identified by ids until all of them reach a terminal state
+// (see OrderState.IsTerminal) or ctx is done.
+//
+// Polls are scoped to the profile of the orders, rather than listing orders of every profile.
+// Pending orders are fetched with GetOrder, at most WithConcurrency at once, until all of them are seen;
+// if they all belong to the same profile, every following poll is a single GetOrders call filtered by it.
+// Orders spanning profiles keep being fetched one by one. With WithWaitProfileID, every poll is
+// a single GetOrders call filtered by the given profile. Orders not found yet are kept pending.
+//
+// The returned map contains the last seen version of every order, so on timeout it holds partial results
+// with the current state of each order; orders not seen at all are missing.
+// On timeout or failed poll, the error is returned along with the partial results.
+func (c *Client) WaitForOrders(ctx context.Context, ids []string, opts ...WaitOption) (map[string]*Order, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("no order IDs to wait for")
+	}
+
+	cfg, err := c.newWaitConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	var req *GetOrdersRequest
+	if cfg.profileID != "" {
+		req = &GetOrdersRequest{ProfileID: cfg.profileID}
+	}
+
+	pending := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		pending[id] = true
+	}
+	total := len(pending)
+	res := make(map[string]*Order, total)
+
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+	for {
+		var os []*Order
+		if req != nil {
+			os, err = c.GetOrders(ctx, req)
+		} else {
+			os, err = c.getOrdersByID(ctx, pending)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return res, ctx.Err()
+			}
+			return res, err
+		}
+		for _, o := range os {
+			if _, ok := pending[o.ID]; !ok {
+				continue
+			}
+			res[o.ID] = o
+			if o.Meta.State.IsTerminal() {
+				delete(pending, o.ID)
+			}
+		}
+		if len(pending) == 0 {
+			return res, nil
+		}
+		if req == nil && len(res) == total {
+			if p := commonProfile(res); p != "" {
+				req = &GetOrdersRequest{ProfileID: p}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return res, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// getOrdersByID fetches orders identified by ids with GetOrder, at most c.concurrency at once.
+// Orders not found are skipped.
+func (c *Client) getOrdersByID(ctx context.Context, ids map[string]bool) ([]*Order, error) {
+	keys := make([]string, 0, len(ids))
+	for id := range ids {
+		keys = append(keys, id)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	found := make([]*Order, len(keys))
+	errs := c.runConcurrently(ctx, len(keys), func(i int) error {
+		o, err := c.GetOrder(ctx, &GetOrderRequest{OrderID: keys[i]})
+		if err != nil {
+			var errResp *ErrorResponse
+			if errors.As(err, &errResp) && errResp.Code == http.StatusNotFound {
+				return nil
+			}
+			cancel()
+			return fmt.Errorf("failed to get order %s: %w", keys[i], err)
+		}
+		found[i] = o
+
+		return nil
+	})
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	os := make([]*Order, 0, len(found))
+	for _, o := range found {
+		if o != nil {
+			os = append(os, o)
+		}
+	}
+
+	return os, nil
+}
+
+// commonProfile returns the profile all orders belong to, or an empty string if they span profiles.
+func commonProfile(orders map[string]*Order) string {
+	var profile string
+	for _, o := range orders {
+		if o.Profile == "" || (profile != "" && o.Profile != profile) {
+			return ""
+		}
+		profile = o.Profile
+	}
+
+	return profile
+}
+
+// WaitForOrderState polls the order identified by orderID until it reaches state or a terminal state
+// (see OrderState.IsTerminal), whichever comes first, or ctx is done.
+// Every poll is a single GetOrder call, so terminal orders can be served WithOrderCache.
+// WithWaitProfileID has no effect here.
+//
+// The last seen version of the order is returned, also on timeout or failed poll along with the error.
+// Check Meta.State of the returned order, as it may be terminal without being state, e.g. rejected.
+func (c *Client) WaitForOrderState(ctx context.Context, orderID string, state OrderState, opts ...WaitOption) (*Order, error) {
+	cfg, err := c.newWaitConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	req := &GetOrderRequest{OrderID: orderID}
+
+	var last *Order
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+	for {
+		o, err := c.GetOrder(ctx, req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return last, ctx.Err()
+			}
+			return last, err
+		}
+		last = o
+		if o.Meta.State == state || o.Meta.State.IsTerminal() {
+			return o, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return last, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ErrOrderRejected is returned by RedeemAndWait when the placed order is rejected.
+var ErrOrderRejected = errors.New("order rejected")
+
+// RedeemAndWait places a redeem order and waits until it's processed or rejected, see WaitForOrderState.
+// The order is polled every pollInterval, zero meaning the interval set by WithPollInterval.
+//
+// Placement errors are returned immediately, without an order. Otherwise the last seen version of the order
+// is returned, together with an error wrapping ErrOrderRejected if it's rejected,
+// or the error of ctx if it's done before the order reaches a terminal state.
+// Note that the order stays placed once ctx is done; it may be processed later.
+func (c *Client) RedeemAndWait(ctx context.Context, req *PlaceOrderRequest, pollInterval time.Duration) (*Order, error) {
+	var opts []WaitOption
+	if pollInterval > 0 {
+		opts = append(opts, WithWaitInterval(pollInterval))
+	}
+	// Fail before placing the order, rather than leave it placed without waiting.
+	if _, err := c.newWaitConfig(opts); err != nil {
+		return nil, err
+	}
+
+	o, err := c.PlaceOrder(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := c.WaitForOrderState(ctx, o.ID, OrderStateProcessed, opts...)
+	if last == nil {
+		last = o
+	}
+	if err != nil {
+		return last, err
+	}
+	if last.Meta.State == OrderStateRejected {
+		return last, fmt.Errorf("order %s: %w", last.ID, ErrOrderRejected)
+	}
+
+	return last, nil
+}
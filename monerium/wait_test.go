@@ -0,0 +1,119 @@
+package monerium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWaitRejectsNonPositiveInterval(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+	}), WithPollInterval(0))
+	ctx := context.Background()
+
+	if _, err := c.WaitForOrders(ctx, []string{"o1"}, WithWaitInterval(-time.Second)); err == nil {
+		t.Error("WaitForOrders: expected error")
+	}
+	if _, err := c.WaitForOrderState(ctx, "o1", OrderStateProcessed); err == nil {
+		t.Error("WaitForOrderState: expected error")
+	}
+	if _, err := c.RedeemAndWait(ctx, &PlaceOrderRequest{}, 0); err == nil {
+		t.Error("RedeemAndWait: expected error")
+	}
+}
+
+func TestWaitForOrdersScopesPollsToProfile(t *testing.T) {
+	var mu sync.Mutex
+	var fetches, lists int
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if id := strings.TrimPrefix(r.URL.Path, "/orders/"); id != r.URL.Path {
+			fetches++
+			writeJSON(t, w, &Order{ID: id, Profile: "p1", Meta: OrderMeta{State: OrderStatePending}})
+			return
+		}
+		lists++
+		if p := r.URL.Query().Get("profile"); p != "p1" {
+			t.Errorf("listed orders of profile %q, want p1", p)
+		}
+		writeJSON(t, w, []*Order{
+			{ID: "o1", Profile: "p1", Meta: OrderMeta{State: OrderStateProcessed}},
+			{ID: "o2", Profile: "p1", Meta: OrderMeta{State: OrderStateProcessed}},
+		})
+	}))
+
+	res, err := c.WaitForOrders(context.Background(), []string{"o1", "o2"}, WithWaitInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("WaitForOrders: %v", err)
+	}
+	if res["o1"].Meta.State != OrderStateProcessed || res["o2"].Meta.State != OrderStateProcessed {
+		t.Errorf("results = %v, want o1 and o2 processed", res)
+	}
+	if fetches != 2 || lists != 1 {
+		t.Errorf("got %d order fetches and %d listings, want 2 and 1", fetches, lists)
+	}
+}
+
+func TestWaitForOrdersMixedCompletionTimes(t *testing.T) {
+	// Fetch at which each order becomes terminal, 0 for never. Orders span profiles,
+	// so they are fetched one by one.
+	completeAt := map[string]int{"o1": 1, "o2": 3, "o3": 0}
+	profiles := map[string]string{"o1": "p1", "o2": "p2", "o3": "p2"}
+	var mu sync.Mutex
+	fetches := make(map[string]int)
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/orders/")
+		if id == r.URL.Path {
+			t.Errorf("unexpected listing %s", r.URL)
+			return
+		}
+		at, ok := completeAt[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			writeJSON(t, w, map[string]interface{}{"code": http.StatusNotFound, "message": "order not found"})
+			return
+		}
+		mu.Lock()
+		fetches[id]++
+		n := fetches[id]
+		mu.Unlock()
+
+		state := OrderStatePending
+		if at > 0 && n >= at {
+			state = OrderStateProcessed
+			if id == "o2" {
+				state = OrderStateRejected
+			}
+		}
+		writeJSON(t, w, &Order{ID: id, Profile: profiles[id], Meta: OrderMeta{State: state}})
+	}))
+
+	res, err := c.WaitForOrders(context.Background(), []string{"o1", "o2"}, WithWaitInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("WaitForOrders: %v", err)
+	}
+	mu.Lock()
+	if fetches["o1"] != 1 || fetches["o2"] != 3 {
+		t.Errorf("got fetches %v, want o1 once and o2 3 times", fetches)
+	}
+	mu.Unlock()
+	if len(res) != 2 || res["o1"].Meta.State != OrderStateProcessed || res["o2"].Meta.State != OrderStateRejected {
+		t.Errorf("results = %v, want o1 processed and o2 rejected", res)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	res, err = c.WaitForOrders(ctx, []string{"o1", "o3", "missing"}, WithWaitInterval(time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if len(res) != 2 || res["o1"].Meta.State != OrderStateProcessed || res["o3"].Meta.State != OrderStatePending {
+		t.Errorf("partial results = %v, want o1 processed and o3 pending", res)
+	}
+}